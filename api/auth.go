@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/spf13/viper"
+)
+
+const (
+	// PermLevelUser perm level of a regular user of the auth service
+	PermLevelUser = 2
+
+	// PermLevelAdmin perm level of an admin, admins can read sensitive columns
+	PermLevelAdmin = 10
+)
+
+var (
+	// ErrUnauthorized error when a request has missing or invalid credentials
+	ErrUnauthorized = fmt.Errorf("unauthorized")
+
+	// ErrForbidden error when the caller does not have the perm level required by the auth policy
+	ErrForbidden = fmt.Errorf("forbidden")
+
+	// authenticators backends tried in order by authenticateRequest
+	authenticators []Authenticator
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	Username string `json:"username"`
+	Perm     int    `json:"perm"`
+	Flags    int    `json:"flags"`
+	Backend  string `json:"backend"`
+}
+
+// Authenticator checks the bearer token of a request against one auth backend
+type Authenticator interface {
+	// Name of the backend, used in config and logs
+	Name() string
+
+	// Authenticate returns the caller for token, or nil with no error when the backend does not know the token
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type authContextKey struct{}
+
+// authResult is stored in the request context by the auth ContextInitializer and checked by the auth RequestValidator
+type authResult struct {
+	principal *Principal
+	err       error
+}
+
+// PrincipalFromContext returns the authenticated caller of a request, or nil for anonymous requests
+func PrincipalFromContext(ctx context.Context) *Principal {
+	result, _ := ctx.Value(authContextKey{}).(*authResult)
+	if result == nil {
+		return nil
+	}
+	return result.principal
+}
+
+// ConfigureAuth builds the backends listed in AUTH_BACKENDS and the AUTH_POLICY, then installs them as the
+// ContextInitializer and RequestValidator. Without AUTH_BACKENDS the remote auth service is used when AUTH_SVC_API is set.
+func ConfigureAuth() error {
+	names := viper.GetString("AUTH_BACKENDS")
+	if names == "" && viper.GetString("AUTH_SVC_API") != "" {
+		names = "remote"
+	}
+
+	authenticators = nil
+	for _, name := range strings.Split(names, ",") {
+		var authenticator Authenticator
+		var err error
+		switch name = strings.TrimSpace(name); name {
+		case "":
+			continue
+		case "remote":
+			authenticator, err = newRemoteAuthenticator()
+		case "static":
+			authenticator, err = newStaticAuthenticator()
+		case "jwt":
+			authenticator, err = newJWTAuthenticator()
+		default:
+			err = fmt.Errorf("unknown auth backend %q, expected remote, static or jwt", name)
+		}
+		if err != nil {
+			return err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	policy, err := ParseAuthPolicy(viper.GetString("AUTH_POLICY"))
+	if err != nil {
+		return err
+	}
+
+	ContextInitializer = authContextInitializer
+	RequestValidator = policy.Validate
+	return nil
+}
+
+// authContextInitializer authenticates the request and stores the outcome in its context
+func authContextInitializer(r *http.Request) context.Context {
+	ctx := r.Context()
+	principal, err := authenticateRequest(ctx, r)
+	ctx = context.WithValue(ctx, authContextKey{}, &authResult{principal: principal, err: err})
+	if principal != nil && principal.Perm >= PermLevelAdmin {
+		ctx = WithRole(ctx, RoleAdmin)
+	}
+	return ctx
+}
+
+// authenticateRequest returns nil with no error for requests without an Authorization header
+func authenticateRequest(ctx context.Context, r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, nil
+	}
+
+	authParts := strings.Split(header, " ")
+	if len(authParts) != 2 || authParts[1] == "" {
+		return nil, fmt.Errorf("%w: invalid authorization header", ErrUnauthorized)
+	}
+
+	// a backend that cannot be reached or rejects the token does not stop the others from recognising it,
+	// so the order of AUTH_BACKENDS never decides whether a valid token is accepted
+	var unavailable, rejected error
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(ctx, authParts[1])
+		switch {
+		case errors.Is(err, ErrAuthUnavailable):
+			if unavailable == nil {
+				unavailable = err
+			}
+			continue
+		case errors.Is(err, ErrUnauthorized):
+			if rejected == nil {
+				rejected = err
+			}
+			continue
+		case err != nil:
+			return nil, err
+		}
+		if principal != nil {
+			principal.Backend = authenticator.Name()
+			return principal, nil
+		}
+	}
+
+	switch {
+	case unavailable != nil:
+		return nil, unavailable
+	case rejected != nil:
+		return nil, rejected
+	default:
+		return nil, fmt.Errorf("%w: invalid api key", ErrUnauthorized)
+	}
+}
+
+// authError returns the authentication error stored in ctx by the auth ContextInitializer
+func authError(ctx context.Context) error {
+	result, _ := ctx.Value(authContextKey{}).(*authResult)
+	if result == nil {
+		return nil
+	}
+	return result.err
+}
+
+// actionName is the name of an action used in AUTH_POLICY
+func actionName(action model.Action) string {
+	switch action {
+	case model.Create:
+		return "create"
+	case model.RetrieveOne:
+		return "retrieve_one"
+	case model.RetrieveMany:
+		return "retrieve_many"
+	case model.Update:
+		return "update"
+	case model.Delete:
+		return "delete"
+	case model.FetchDDL:
+		return "fetch_ddl"
+	default:
+		return ""
+	}
+}
+
+// actionGroup is read for actions that do not change data and write for the others
+func actionGroup(action model.Action) string {
+	switch action {
+	case model.Create, model.Update, model.Delete:
+		return "write"
+	default:
+		return "read"
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/model"
+)
+
+// defaultAuthPolicy keeps tables and /ddl public to read, table writes, the cache, the schedules and the view and
+// refresh job inventory for admins and view refreshes for any authenticated caller. AUTH_POLICY entries are applied on top of it.
+const defaultAuthPolicy = "*.read=public,*.write=admin,views.read=admin,views.write=user,cache.*=admin,schedules.*=admin"
+
+// Requirement is the access needed for an action on a table
+type Requirement struct {
+	// Authenticated a principal is required
+	Authenticated bool
+
+	// MinPerm the minimum perm level of the principal
+	MinPerm int
+}
+
+// AuthPolicy maps "table.action" keys to requirements, table may be * and action may be read, write or *
+type AuthPolicy map[string]Requirement
+
+// ParseAuthPolicy parses a comma separated list of table.action=level entries on top of the default policy.
+// level is public, user (any authenticated caller), admin or a perm level number.
+// Actions are create, retrieve_one, retrieve_many, update, delete, fetch_ddl, read, write or *.
+// e.g. wallet_logs.read=admin,log_events.write=user
+func ParseAuthPolicy(s string) (AuthPolicy, error) {
+	policy := make(AuthPolicy)
+	for _, entry := range strings.Split(defaultAuthPolicy+","+s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !strings.Contains(parts[0], ".") {
+			return nil, fmt.Errorf("invalid auth policy entry %q, expected table.action=level", entry)
+		}
+
+		requirement, err := parseRequirement(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth policy entry %q: %w", entry, err)
+		}
+		policy[strings.TrimSpace(parts[0])] = requirement
+	}
+
+	return policy, nil
+}
+
+func parseRequirement(level string) (Requirement, error) {
+	switch level {
+	case "public":
+		return Requirement{}, nil
+	case "user":
+		return Requirement{Authenticated: true}, nil
+	case "admin":
+		return Requirement{Authenticated: true, MinPerm: PermLevelAdmin}, nil
+	default:
+		perm, err := strconv.Atoi(level)
+		if err != nil {
+			return Requirement{}, fmt.Errorf("level must be public, user, admin or a number")
+		}
+		return Requirement{Authenticated: true, MinPerm: perm}, nil
+	}
+}
+
+// Requirement returns the most specific requirement for action on table, table entries win over * entries
+// and action entries win over read/write entries.
+func (p AuthPolicy) Requirement(table string, action model.Action) Requirement {
+	for _, t := range []string{table, "*"} {
+		for _, a := range []string{actionName(action), actionGroup(action), "*"} {
+			if requirement, ok := p[t+"."+a]; ok {
+				return requirement
+			}
+		}
+	}
+
+	return Requirement{Authenticated: true, MinPerm: PermLevelAdmin}
+}
+
+// Validate is a RequestValidatorFunc enforcing the policy with the principal stored by the auth ContextInitializer.
+// Public actions are served to anyone, a caller whose credentials failed to authenticate is treated as anonymous.
+func (p AuthPolicy) Validate(ctx context.Context, r *http.Request, table string, action model.Action) error {
+	requirement := p.Requirement(table, action)
+	if !requirement.Authenticated {
+		return nil
+	}
+
+	if err := authError(ctx); err != nil {
+		return err
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return fmt.Errorf("%w: authorization required", ErrUnauthorized)
+	}
+
+	if principal.Perm < requirement.MinPerm {
+		return fmt.Errorf("%w: %s on %s requires perm level %d", ErrForbidden, actionName(action), table, requirement.MinPerm)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	explru "github.com/paskal/golang-lru/simplelru"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultAuthSvcTimeout       = 5 * time.Second
+	defaultAuthCacheTTL         = time.Minute
+	defaultAuthNegativeCacheTTL = 10 * time.Second
+	defaultAuthBreakerThreshold = 5
+	defaultAuthBreakerCooldown  = 30 * time.Second
+
+	authCacheSize = 10000
+)
+
+// ErrAuthUnavailable error when the auth service cannot be reached, times out, answers with an
+// unexpected status or the circuit breaker is open
+var ErrAuthUnavailable = fmt.Errorf("auth service unavailable")
+
+// remoteAuthenticator checks tokens with the check-api-key endpoint of the auth service at AUTH_SVC_API.
+// Answers are cached by token hash, valid tokens for AUTH_CACHE_TTL and rejected tokens for AUTH_NEGATIVE_CACHE_TTL.
+type remoteAuthenticator struct {
+	url     string
+	client  *http.Client
+	breaker *circuitBreaker
+
+	validated *explru.ExpirableLRU
+	rejected  *explru.ExpirableLRU
+}
+
+func newRemoteAuthenticator() (Authenticator, error) {
+	authSvcApi := viper.GetString("AUTH_SVC_API")
+	if authSvcApi == "" {
+		return nil, fmt.Errorf("auth backend remote requires AUTH_SVC_API")
+	}
+
+	timeout, err := configDuration("AUTH_SVC_TIMEOUT", defaultAuthSvcTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, err := configDuration("AUTH_CACHE_TTL", defaultAuthCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	negativeCacheTTL, err := configDuration("AUTH_NEGATIVE_CACHE_TTL", defaultAuthNegativeCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	cooldown, err := configDuration("AUTH_BREAKER_COOLDOWN", defaultAuthBreakerCooldown)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := defaultAuthBreakerThreshold
+	if viper.IsSet("AUTH_BREAKER_THRESHOLD") {
+		value := viper.GetString("AUTH_BREAKER_THRESHOLD")
+		if threshold, err = strconv.Atoi(value); err != nil || threshold < 1 {
+			return nil, fmt.Errorf("invalid AUTH_BREAKER_THRESHOLD %q, expected a number of failed calls of 1 or more", value)
+		}
+	}
+
+	authenticator := &remoteAuthenticator{
+		url:     authSvcApi + "/check-api-key",
+		client:  &http.Client{Timeout: timeout},
+		breaker: newCircuitBreaker(threshold, cooldown),
+	}
+	if cacheTTL > 0 {
+		authenticator.validated = explru.NewExpirableLRU(authCacheSize, nil, cacheTTL, cacheTTL)
+	}
+	if negativeCacheTTL > 0 {
+		authenticator.rejected = explru.NewExpirableLRU(authCacheSize, nil, negativeCacheTTL, negativeCacheTTL)
+	}
+
+	return authenticator, nil
+}
+
+// configDuration reads a duration such as 5s or 1m from config, falling back to def when unset
+// error - the value is not a duration or is negative
+func configDuration(key string, def time.Duration) (time.Duration, error) {
+	if !viper.IsSet(key) {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(viper.GetString(key))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a duration such as 5s", key, viper.GetString(key))
+	}
+	return d, nil
+}
+
+func (a *remoteAuthenticator) Name() string {
+	return "remote"
+}
+
+func (a *remoteAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	key := tokenHash(token)
+	if a.validated != nil {
+		if val, ok := a.validated.Get(key); ok {
+			principal := *val.(*Principal)
+			return &principal, nil
+		}
+	}
+	if a.rejected != nil {
+		if _, ok := a.rejected.Get(key); ok {
+			return nil, nil
+		}
+	}
+
+	if !a.breaker.Allow() {
+		return nil, fmt.Errorf("%w: circuit open", ErrAuthUnavailable)
+	}
+
+	principal, err := a.checkAPIKey(ctx, token)
+	if err != nil {
+		// a caller that went away says nothing about the health of the auth service
+		if ctx.Err() != nil {
+			a.breaker.Cancel()
+			return nil, ctx.Err()
+		}
+		a.breaker.Failure()
+		return nil, err
+	}
+	a.breaker.Success()
+
+	if principal == nil {
+		if a.rejected != nil {
+			a.rejected.Add(key, true)
+		}
+		return nil, nil
+	}
+
+	if a.validated != nil {
+		cached := *principal
+		a.validated.Add(key, &cached)
+	}
+	return principal, nil
+}
+
+// checkAPIKey asks the auth service about token, a rejected token returns nil with no error
+func (a *remoteAuthenticator) checkAPIKey(ctx context.Context, token string) (*Principal, error) {
+	body, err := json.Marshal(struct {
+		Token string `json:"token"`
+	}{Token: token})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		io.Copy(io.Discard, response.Body)
+		return nil, nil
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		io.Copy(io.Discard, response.Body)
+		return nil, fmt.Errorf("%w: check-api-key returned status %d", ErrAuthUnavailable, response.StatusCode)
+	}
+
+	authResp, err := GetAuthResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+	}
+
+	if !authResp.Result.Validated {
+		return nil, nil
+	}
+
+	return &Principal{
+		Username: authResp.User.Username,
+		Perm:     authResp.User.Perm,
+		Flags:    authResp.User.Flags,
+	}, nil
+}
+
+// tokenHash is the cache key of a token, so raw tokens are not kept in memory
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type AuthResponse struct {
+	User struct {
+		Username string `json:"username"`
+		Perm     int    `json:"perm"`
+		Flags    int    `json:"flags"`
+	} `json:"user"`
+	Result struct {
+		Validated bool   `json:"validated"`
+		Details   string `json:"details"`
+	} `json:"result"`
+}
+
+// GetAuthResponse decodes the body of a check-api-key response
+// error - the body is not a valid AuthResponse
+func GetAuthResponse(resp *http.Response) (AuthResponse, error) {
+	jsonBody := AuthResponse{}
+	err := json.NewDecoder(resp.Body).Decode(&jsonBody)
+	if err != nil {
+		return AuthResponse{}, fmt.Errorf("unable to decode auth response: %w", err)
+	}
+	return jsonBody, nil
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	explru "github.com/paskal/golang-lru/simplelru"
+	"github.com/spf13/viper"
+)
+
+// fakeAuthService answers check-api-key: good is a valid key, bad an invalid one, revoked is refused with 401
+// and down fails with 500
+type fakeAuthService struct {
+	*httptest.Server
+	calls int64
+}
+
+func newFakeAuthService(t *testing.T) *fakeAuthService {
+	t.Helper()
+
+	s := &fakeAuthService{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.calls, 1)
+
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.URL.Path != "/check-api-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var resp AuthResponse
+		switch body.Token {
+		case "good":
+			resp.Result.Validated = true
+			resp.User.Username = "alice"
+			resp.User.Perm = 10
+		case "bad":
+		case "revoked":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeAuthService) callCount() int64 {
+	return atomic.LoadInt64(&s.calls)
+}
+
+func newTestRemoteAuthenticator(s *fakeAuthService, cacheTTL, negativeCacheTTL time.Duration, threshold int) *remoteAuthenticator {
+	a := &remoteAuthenticator{
+		url:     s.URL + "/check-api-key",
+		client:  s.Client(),
+		breaker: newCircuitBreaker(threshold, time.Minute),
+	}
+	if cacheTTL > 0 {
+		a.validated = explru.NewExpirableLRU(authCacheSize, nil, cacheTTL, cacheTTL)
+	}
+	if negativeCacheTTL > 0 {
+		a.rejected = explru.NewExpirableLRU(authCacheSize, nil, negativeCacheTTL, negativeCacheTTL)
+	}
+	return a
+}
+
+func TestRemoteAuthenticatorCache(t *testing.T) {
+	service := newFakeAuthService(t)
+	a := newTestRemoteAuthenticator(service, time.Hour, time.Hour, 5)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		principal, err := a.Authenticate(ctx, "good")
+		if err != nil || principal == nil || principal.Username != "alice" || principal.Perm != 10 {
+			t.Fatalf("Authenticate(good) = %+v, %v", principal, err)
+		}
+		// callers get a copy, changing it does not change the cached principal
+		principal.Perm = 0
+	}
+	if n := service.callCount(); n != 1 {
+		t.Errorf("valid token checked %d times, want 1", n)
+	}
+
+	for _, token := range []string{"bad", "revoked"} {
+		for i := 0; i < 3; i++ {
+			if principal, err := a.Authenticate(ctx, token); err != nil || principal != nil {
+				t.Fatalf("Authenticate(%s) = %+v, %v, want nil, nil", token, principal, err)
+			}
+		}
+	}
+	if n := service.callCount(); n != 3 {
+		t.Errorf("service called %d times, want one call per token", n)
+	}
+}
+
+func TestRemoteAuthenticatorCacheExpiry(t *testing.T) {
+	service := newFakeAuthService(t)
+	a := newTestRemoteAuthenticator(service, 20*time.Millisecond, 20*time.Millisecond, 5)
+	ctx := context.Background()
+
+	for _, token := range []string{"good", "bad"} {
+		if _, err := a.Authenticate(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, token := range []string{"good", "bad"} {
+		if _, err := a.Authenticate(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := service.callCount(); n != 4 {
+		t.Errorf("service called %d times, want the expired answers checked again", n)
+	}
+
+	uncached := newTestRemoteAuthenticator(service, 0, 0, 5)
+	for i := 0; i < 2; i++ {
+		if _, err := uncached.Authenticate(ctx, "good"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := service.callCount(); n != 6 {
+		t.Errorf("service called %d times, want every call checked without a cache", n)
+	}
+}
+
+func TestRemoteAuthenticatorUnavailable(t *testing.T) {
+	service := newFakeAuthService(t)
+	a := newTestRemoteAuthenticator(service, time.Hour, time.Hour, 2)
+	ctx := context.Background()
+
+	// failures are not cached, they count towards the breaker
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authenticate(ctx, "down"); !errors.Is(err, ErrAuthUnavailable) {
+			t.Fatalf("Authenticate(down) error %v, want ErrAuthUnavailable", err)
+		}
+	}
+	if n := service.callCount(); n != 2 {
+		t.Fatalf("service called %d times, want 2", n)
+	}
+
+	_, err := a.Authenticate(ctx, "good")
+	if !errors.Is(err, ErrAuthUnavailable) || !strings.Contains(err.Error(), "circuit open") {
+		t.Errorf("Authenticate with an open breaker error %v, want circuit open", err)
+	}
+	if n := service.callCount(); n != 2 {
+		t.Errorf("service called %d times with an open breaker", n)
+	}
+}
+
+func TestRemoteAuthenticatorCanceled(t *testing.T) {
+	service := newFakeAuthService(t)
+	a := newTestRemoteAuthenticator(service, time.Hour, time.Hour, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.Authenticate(ctx, "good"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Authenticate with a canceled context error %v", err)
+	}
+
+	// a caller going away does not open the breaker
+	if principal, err := a.Authenticate(context.Background(), "good"); err != nil || principal == nil {
+		t.Errorf("Authenticate after a canceled call = %+v, %v", principal, err)
+	}
+}
+
+func TestNewRemoteAuthenticatorConfig(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		err   string
+	}{
+		{"AUTH_SVC_TIMEOUT", "soon", "invalid AUTH_SVC_TIMEOUT"},
+		{"AUTH_CACHE_TTL", "-1m", "invalid AUTH_CACHE_TTL"},
+		{"AUTH_NEGATIVE_CACHE_TTL", "10", "invalid AUTH_NEGATIVE_CACHE_TTL"},
+		{"AUTH_BREAKER_COOLDOWN", "-30s", "invalid AUTH_BREAKER_COOLDOWN"},
+		{"AUTH_BREAKER_THRESHOLD", "0", "invalid AUTH_BREAKER_THRESHOLD"},
+		{"AUTH_BREAKER_THRESHOLD", "many", "invalid AUTH_BREAKER_THRESHOLD"},
+		{"AUTH_CACHE_TTL", "0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("AUTH_SVC_API", "http://auth.invalid")
+			viper.Set(tt.key, tt.value)
+
+			_, err := newRemoteAuthenticator()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("newRemoteAuthenticator() error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("newRemoteAuthenticator() error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// staticAuthenticator checks tokens against the keys listed in AUTH_STATIC_KEYS
+type staticAuthenticator struct {
+	keys map[string]Principal
+}
+
+// newStaticAuthenticator parses AUTH_STATIC_KEYS, a comma separated list of key:perm[:username]
+func newStaticAuthenticator() (Authenticator, error) {
+	a := &staticAuthenticator{keys: make(map[string]Principal)}
+	for _, entry := range strings.Split(viper.GetString("AUTH_STATIC_KEYS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid AUTH_STATIC_KEYS entry, expected key:perm[:username]")
+		}
+
+		perm, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTH_STATIC_KEYS perm %q", parts[1])
+		}
+
+		principal := Principal{Username: "static", Perm: perm}
+		if len(parts) == 3 && parts[2] != "" {
+			principal.Username = parts[2]
+		}
+		a.keys[parts[0]] = principal
+	}
+
+	if len(a.keys) == 0 {
+		return nil, fmt.Errorf("auth backend static requires AUTH_STATIC_KEYS")
+	}
+
+	return a, nil
+}
+
+func (a *staticAuthenticator) Name() string {
+	return "static"
+}
+
+func (a *staticAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	for key, principal := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			p := principal
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
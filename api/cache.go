@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CacheInfo is the state of the stats cache
+type CacheInfo struct {
+	Stats dao.CacheStats `json:"stats"`
+	Keys  []string       `json:"keys"`
+}
+
+// CacheFlushResponse lists the keys removed from the stats cache, all when the whole cache was flushed
+type CacheFlushResponse struct {
+	Flushed bool     `json:"flushed"`
+	Keys    []string `json:"keys,omitempty"`
+}
+
+func configGinCacheRouter(router gin.IRoutes) {
+	router.GET("/admin/cache", ConverHttprouterToGin(GetCache))
+	router.DELETE("/admin/cache", ConverHttprouterToGin(FlushCache))
+}
+
+// GetCache returns the counters and live keys of the stats cache
+// @Summary Stats cache counters and keys
+// @Tags Cache
+// @Produce  json
+// @Success 200 {object} api.CacheInfo
+// @Failure 401 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /admin/cache [get]
+func GetCache(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, "cache", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	keys, err := dao.Cacher.Keys()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, &CacheInfo{Stats: dao.Cacher.Stats(), Keys: keys})
+}
+
+// FlushCache removes the given keys from the stats cache, or every key when none is given
+// @Summary Flush the stats cache
+// @Tags Cache
+// @Produce  json
+// @Param   key     query    string  false  "key to remove, may be repeated, all keys when omitted"
+// @Success 200 {object} api.CacheFlushResponse
+// @Failure 401 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /admin/cache [delete]
+func FlushCache(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, "cache", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	keys := r.URL.Query()["key"]
+	if len(keys) > 0 {
+		if err := dao.Cacher.Delete(keys...); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+		writeJSON(ctx, w, &CacheFlushResponse{Keys: keys})
+		return
+	}
+
+	if err := dao.Cacher.Flush(); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	writeJSON(ctx, w, &CacheFlushResponse{Flushed: true})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker fails calls fast after threshold consecutive failures. Once cooldown has passed a single
+// trial call is let through, its success closes the breaker and its failure opens it for another cooldown.
+type circuitBreaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	failures  int
+	openUntil time.Time
+	probing   bool
+
+	// now is the clock of the cooldown, replaced in tests
+	now func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether a call may be made, a false result means the breaker is open
+func (b *circuitBreaker) Allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// Success records a successful call and closes the breaker
+func (b *circuitBreaker) Success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// Cancel records a call that ended without telling whether the service is healthy
+func (b *circuitBreaker) Cancel() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.probing = false
+}
+
+// Failure records a failed call, opening the breaker once threshold is reached
+func (b *circuitBreaker) Failure() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
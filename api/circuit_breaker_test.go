@@ -0,0 +1,110 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for the breaker cooldown
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	breaker := newCircuitBreaker(threshold, cooldown)
+	breaker.now = clock.now
+	return breaker, clock
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker, clock := newTestBreaker(2, time.Minute)
+
+	// closed, failures below the threshold still let calls through
+	if !breaker.Allow() {
+		t.Fatal("new breaker is not closed")
+	}
+	breaker.Failure()
+	if !breaker.Allow() {
+		t.Fatal("breaker opened before the threshold")
+	}
+
+	// open for the cooldown
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("breaker is closed after reaching the threshold")
+	}
+	clock.advance(59 * time.Second)
+	if breaker.Allow() {
+		t.Fatal("breaker let a call through before the cooldown passed")
+	}
+
+	// half open, a single trial call
+	clock.advance(2 * time.Second)
+	if !breaker.Allow() {
+		t.Fatal("breaker did not let a trial call through after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatal("breaker let a second call through while the trial call runs")
+	}
+
+	// a failed trial opens it for another cooldown
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("breaker is closed after a failed trial call")
+	}
+	clock.advance(time.Minute + time.Second)
+	if !breaker.Allow() {
+		t.Fatal("breaker did not let a trial call through after the second cooldown")
+	}
+
+	// a successful trial closes it and resets the failure count
+	breaker.Success()
+	for i := 0; i < 3; i++ {
+		if !breaker.Allow() {
+			t.Fatalf("call %d refused after a successful trial call", i)
+		}
+	}
+	breaker.Failure()
+	if !breaker.Allow() {
+		t.Fatal("a single failure after closing opened the breaker")
+	}
+}
+
+func TestCircuitBreakerCancel(t *testing.T) {
+	breaker, clock := newTestBreaker(1, time.Minute)
+
+	breaker.Failure()
+	clock.advance(2 * time.Minute)
+	if !breaker.Allow() {
+		t.Fatal("breaker did not let a trial call through after the cooldown")
+	}
+
+	// a canceled trial neither closes nor reopens the breaker, the next call is a new trial
+	breaker.Cancel()
+	if !breaker.Allow() {
+		t.Fatal("breaker refused a new trial call after a canceled one")
+	}
+	if breaker.Allow() {
+		t.Fatal("breaker closed after a canceled trial call")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker, _ := newTestBreaker(0, time.Minute)
+
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	if !breaker.Allow() {
+		t.Error("breaker without a threshold refused a call")
+	}
+}
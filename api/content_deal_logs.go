@@ -20,9 +20,12 @@ func configContentDealLogsRouter(router *httprouter.Router) {
 	router.GET("/contentdeallogs/:contentDealLogsID", GetContentDealLogs)
 }
 
-func configGinContentDealLogsRouter(router gin.IRoutes) {
-	router.GET("/contentdeallogs", ConverHttprouterToGin(GetAllContentDealLogs))
-	router.GET("/contentdeallogs/:contentDealLogsID", ConverHttprouterToGin(GetContentDealLogs))
+func configGinContentDealLogsRouter(router gin.IRoutes, mode CrudMode) {
+	mountCrudRoute(router, mode, model.RetrieveMany, http.MethodGet, "/contentdeallogs", GetAllContentDealLogs)
+	mountCrudRoute(router, mode, model.RetrieveOne, http.MethodGet, "/contentdeallogs/:contentDealLogsID", GetContentDealLogs)
+	mountCrudRoute(router, mode, model.Create, http.MethodPost, "/contentdeallogs", AddContentDealLogs)
+	mountCrudRoute(router, mode, model.Update, http.MethodPut, "/contentdeallogs/:contentDealLogsID", UpdateContentDealLogs)
+	mountCrudRoute(router, mode, model.Delete, http.MethodDelete, "/contentdeallogs/:contentDealLogsID", DeleteContentDealLogs)
 }
 
 // GetAllContentDealLogs is a function to get a slice of record(s) from content_deal_logs table in the estuary database
@@ -33,7 +36,12 @@ func configGinContentDealLogsRouter(router gin.IRoutes) {
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "db sort order, e.g. created_at desc,id asc"
+// @Param   sort     query    string  false        "db sort order, e.g. -created_at,id"
+// @Param   cursor   query    string  false        "page with a keyset cursor, empty for the first page then the nextCursor of the previous page"
+// @Param   count    query    string  false        "total record count: true (default), false or estimate"
+// @Param   fields   query    string  false        "comma separated columns to return, e.g. id,miner,created_at"
+// @Param   column   query    string  false        "column filter as <column>=<op>:<value>, op is one of eq, ne, lt, lte, gt, gte, in, nin, like, ilike, isnull"
 // @Success 200 {object} api.PagedResults{data=[]model.ContentDealLogs}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -41,32 +49,25 @@ func configGinContentDealLogsRouter(router gin.IRoutes) {
 // http "http://localhost:8080/contentdeallogs?page=0&pagesize=20" X-Api-User:user123
 func GetAllContentDealLogs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
-	page, err := readInt(r, "page", 0)
-	if err != nil || page < 0 {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
-	}
-
-	pagesize, err := readInt(r, "pagesize", 20)
-	if err != nil || pagesize <= 0 {
-		returnError(ctx, w, r, dao.ErrBadParams)
+	query, err := readListQuery(ctx, r, "content_deal_logs")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
-	order := r.FormValue("order")
-
 	if err := ValidateRequest(ctx, r, "content_deal_logs", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllContentDealLogs(ctx, page, pagesize, order)
+	records, totalRows, nextCursor, err := dao.GetAllContentDealLogs(ctx, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	result := &PagedResults{Page: query.Page, PageSize: query.PageSize, Data: renderRecords(ctx, "content_deal_logs", records, query.Fields), TotalRecords: totalRows,
+		TotalEstimated: query.Count == dao.CountEstimate && totalRows >= 0, NextCursor: nextCursor}
 	writeJSON(ctx, w, result)
 }
 
@@ -78,6 +79,7 @@ func GetAllContentDealLogs(w http.ResponseWriter, r *http.Request, ps httprouter
 // @Accept  json
 // @Produce  json
 // @Param  contentDealLogsID path int64 true "id"
+// @Param  fields query string false "comma separated columns to return, e.g. id,miner,created_at"
 // @Success 200 {object} model.ContentDealLogs
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
@@ -92,18 +94,24 @@ func GetContentDealLogs(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
+	fields, err := readFields(ctx, r, "content_deal_logs")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	if err := ValidateRequest(ctx, r, "content_deal_logs", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	record, err := dao.GetContentDealLogs(ctx, contentDealLogsID)
+	record, err := dao.GetContentDealLogs(ctx, contentDealLogsID, fields)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_logs", record, fields))
 }
 
 // AddContentDealLogs add to add a single record to content_deal_logs table in the estuary database
@@ -150,7 +158,7 @@ func AddContentDealLogs(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
-	writeJSON(ctx, w, contentdeallogs)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_logs", contentdeallogs, nil))
 }
 
 // UpdateContentDealLogs Update a single record from content_deal_logs table in the estuary database
@@ -205,7 +213,7 @@ func UpdateContentDealLogs(w http.ResponseWriter, r *http.Request, ps httprouter
 		return
 	}
 
-	writeJSON(ctx, w, contentdeallogs)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_logs", contentdeallogs, nil))
 }
 
 // DeleteContentDealLogs Delete a single record from content_deal_logs table in the estuary database
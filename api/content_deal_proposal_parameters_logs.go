@@ -20,9 +20,12 @@ func configContentDealProposalParametersLogsRouter(router *httprouter.Router) {
 	router.GET("/contentdealproposalparameterslogs/:contentDealProposalParametersLogsID", GetContentDealProposalParametersLogs)
 }
 
-func configGinContentDealProposalParametersLogsRouter(router gin.IRoutes) {
-	router.GET("/contentdealproposalparameterslogs", ConverHttprouterToGin(GetAllContentDealProposalParametersLogs))
-	router.GET("/contentdealproposalparameterslogs/:contentDealProposalParametersLogsID", ConverHttprouterToGin(GetContentDealProposalParametersLogs))
+func configGinContentDealProposalParametersLogsRouter(router gin.IRoutes, mode CrudMode) {
+	mountCrudRoute(router, mode, model.RetrieveMany, http.MethodGet, "/contentdealproposalparameterslogs", GetAllContentDealProposalParametersLogs)
+	mountCrudRoute(router, mode, model.RetrieveOne, http.MethodGet, "/contentdealproposalparameterslogs/:contentDealProposalParametersLogsID", GetContentDealProposalParametersLogs)
+	mountCrudRoute(router, mode, model.Create, http.MethodPost, "/contentdealproposalparameterslogs", AddContentDealProposalParametersLogs)
+	mountCrudRoute(router, mode, model.Update, http.MethodPut, "/contentdealproposalparameterslogs/:contentDealProposalParametersLogsID", UpdateContentDealProposalParametersLogs)
+	mountCrudRoute(router, mode, model.Delete, http.MethodDelete, "/contentdealproposalparameterslogs/:contentDealProposalParametersLogsID", DeleteContentDealProposalParametersLogs)
 }
 
 // GetAllContentDealProposalParametersLogs is a function to get a slice of record(s) from content_deal_proposal_parameters_logs table in the estuary database
@@ -33,7 +36,12 @@ func configGinContentDealProposalParametersLogsRouter(router gin.IRoutes) {
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "db sort order, e.g. created_at desc,id asc"
+// @Param   sort     query    string  false        "db sort order, e.g. -created_at,id"
+// @Param   cursor   query    string  false        "page with a keyset cursor, empty for the first page then the nextCursor of the previous page"
+// @Param   count    query    string  false        "total record count: true (default), false or estimate"
+// @Param   fields   query    string  false        "comma separated columns to return, e.g. id,miner,created_at"
+// @Param   column   query    string  false        "column filter as <column>=<op>:<value>, op is one of eq, ne, lt, lte, gt, gte, in, nin, like, ilike, isnull"
 // @Success 200 {object} api.PagedResults{data=[]model.ContentDealProposalParametersLogs}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -41,32 +49,25 @@ func configGinContentDealProposalParametersLogsRouter(router gin.IRoutes) {
 // http "http://localhost:8080/contentdealproposalparameterslogs?page=0&pagesize=20" X-Api-User:user123
 func GetAllContentDealProposalParametersLogs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
-	page, err := readInt(r, "page", 0)
-	if err != nil || page < 0 {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
-	}
-
-	pagesize, err := readInt(r, "pagesize", 20)
-	if err != nil || pagesize <= 0 {
-		returnError(ctx, w, r, dao.ErrBadParams)
+	query, err := readListQuery(ctx, r, "content_deal_proposal_parameters_logs")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
-	order := r.FormValue("order")
-
 	if err := ValidateRequest(ctx, r, "content_deal_proposal_parameters_logs", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllContentDealProposalParametersLogs(ctx, page, pagesize, order)
+	records, totalRows, nextCursor, err := dao.GetAllContentDealProposalParametersLogs(ctx, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	result := &PagedResults{Page: query.Page, PageSize: query.PageSize, Data: renderRecords(ctx, "content_deal_proposal_parameters_logs", records, query.Fields), TotalRecords: totalRows,
+		TotalEstimated: query.Count == dao.CountEstimate && totalRows >= 0, NextCursor: nextCursor}
 	writeJSON(ctx, w, result)
 }
 
@@ -78,6 +79,7 @@ func GetAllContentDealProposalParametersLogs(w http.ResponseWriter, r *http.Requ
 // @Accept  json
 // @Produce  json
 // @Param  contentDealProposalParametersLogsID path int64 true "id"
+// @Param  fields query string false "comma separated columns to return, e.g. id,miner,created_at"
 // @Success 200 {object} model.ContentDealProposalParametersLogs
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
@@ -92,18 +94,24 @@ func GetContentDealProposalParametersLogs(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	fields, err := readFields(ctx, r, "content_deal_proposal_parameters_logs")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	if err := ValidateRequest(ctx, r, "content_deal_proposal_parameters_logs", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	record, err := dao.GetContentDealProposalParametersLogs(ctx, contentDealProposalParametersLogsID)
+	record, err := dao.GetContentDealProposalParametersLogs(ctx, contentDealProposalParametersLogsID, fields)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_proposal_parameters_logs", record, fields))
 }
 
 // AddContentDealProposalParametersLogs add to add a single record to content_deal_proposal_parameters_logs table in the estuary database
@@ -150,7 +158,7 @@ func AddContentDealProposalParametersLogs(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	writeJSON(ctx, w, contentdealproposalparameterslogs)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_proposal_parameters_logs", contentdealproposalparameterslogs, nil))
 }
 
 // UpdateContentDealProposalParametersLogs Update a single record from content_deal_proposal_parameters_logs table in the estuary database
@@ -205,7 +213,7 @@ func UpdateContentDealProposalParametersLogs(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	writeJSON(ctx, w, contentdealproposalparameterslogs)
+	writeJSON(ctx, w, renderRecords(ctx, "content_deal_proposal_parameters_logs", contentdealproposalparameterslogs, nil))
 }
 
 // DeleteContentDealProposalParametersLogs Delete a single record from content_deal_proposal_parameters_logs table in the estuary database
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+)
+
+// CrudMode controls which of the generated table routes are mounted.
+type CrudMode string
+
+const (
+	// CrudModeOff mounts no routes for the table
+	CrudModeOff = CrudMode("off")
+
+	// CrudModeRead mounts the list and get-by-id routes for the table
+	CrudModeRead = CrudMode("read")
+
+	// CrudModeFull mounts the read routes plus create, update and delete
+	CrudModeFull = CrudMode("full")
+)
+
+// mountedCrudEndpoints is the view of crudEndpoints served by /ddl, it only lists routes that are mounted
+var mountedCrudEndpoints = make(map[string]*CrudAPI)
+
+// ParseCrudMode parses a CRUD_MODE config value, an empty value is treated as off
+func ParseCrudMode(s string) (CrudMode, error) {
+	switch mode := CrudMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", CrudModeOff:
+		return CrudModeOff, nil
+	case CrudModeRead, CrudModeFull:
+		return mode, nil
+	default:
+		return CrudModeOff, fmt.Errorf("invalid crud mode %q, expected one of off, read, full", s)
+	}
+}
+
+// Allows reports whether routes for action are mounted in this mode
+func (m CrudMode) Allows(action model.Action) bool {
+	switch m {
+	case CrudModeFull:
+		return action == model.Create || action == model.RetrieveOne || action == model.RetrieveMany ||
+			action == model.Update || action == model.Delete
+	case CrudModeRead:
+		return action == model.RetrieveOne || action == model.RetrieveMany
+	default:
+		return false
+	}
+}
+
+// crudModeFor looks up the mode for a table, CRUD_MODE_<TABLE> overrides the global CRUD_MODE
+func crudModeFor(table string) (CrudMode, error) {
+	key := "CRUD_MODE_" + strings.ToUpper(table)
+	if viper.IsSet(key) {
+		return ParseCrudMode(viper.GetString(key))
+	}
+
+	return ParseCrudMode(viper.GetString("CRUD_MODE"))
+}
+
+// configGinCrudRouter mounts the generated table routes allowed by config and records them for /ddl
+func configGinCrudRouter(router gin.IRoutes) error {
+	tables := make([]string, 0, len(crudEndpoints))
+	for table := range crudEndpoints {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		mode, err := crudModeFor(table)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+
+		if mode == CrudModeOff {
+			continue
+		}
+
+		endpoint := crudEndpoints[table]
+		endpoint.mount(router, mode)
+		mountedCrudEndpoints[table] = mountedCrudAPI(endpoint, mode)
+	}
+
+	return nil
+}
+
+// mountCrudRoute mounts handler for action if the mode allows it
+func mountCrudRoute(router gin.IRoutes, mode CrudMode, action model.Action, method, path string, handler httprouter.Handle) {
+	if !mode.Allows(action) {
+		return
+	}
+
+	router.Handle(method, path, ConverHttprouterToGin(handler))
+}
+
+// mountedCrudAPI copies endpoint, clearing the urls of actions that are not mounted
+func mountedCrudAPI(endpoint *CrudAPI, mode CrudMode) *CrudAPI {
+	mounted := *endpoint
+	mounted.Mode = mode
+
+	if !mode.Allows(model.Create) {
+		mounted.CreateURL = ""
+	}
+	if !mode.Allows(model.RetrieveOne) {
+		mounted.RetrieveOneURL = ""
+	}
+	if !mode.Allows(model.RetrieveMany) {
+		mounted.RetrieveManyURL = ""
+	}
+	if !mode.Allows(model.Update) {
+		mounted.UpdateURL = ""
+	}
+	if !mode.Allows(model.Delete) {
+		mounted.DeleteURL = ""
+	}
+
+	return &mounted
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultStatsMaxAge max-age sent with stats responses when STATS_MAX_AGE is not set
+const defaultStatsMaxAge = 5 * time.Minute
+
+// statsMaxAge max-age of the Cache-Control header of stats responses
+var statsMaxAge = defaultStatsMaxAge
+
+// ConfigureHTTPCache reads STATS_MAX_AGE, a duration such as 5m, 0 makes stats responses revalidate every time
+func ConfigureHTTPCache() error {
+	if !viper.IsSet("STATS_MAX_AGE") {
+		return nil
+	}
+
+	maxAge, err := time.ParseDuration(viper.GetString("STATS_MAX_AGE"))
+	if err != nil || maxAge < 0 {
+		return fmt.Errorf("invalid STATS_MAX_AGE %q, expected a duration such as 5m", viper.GetString("STATS_MAX_AGE"))
+	}
+	statsMaxAge = maxAge
+	return nil
+}
+
+// writeCachableJSON writes v with an ETag from its content, a Last-Modified of lastModified unless it is zero and a
+// public max-age. It answers 304 without a body when the request's If-None-Match or If-Modified-Since still match.
+func writeCachableJSON(ctx context.Context, w http.ResponseWriter, r *http.Request, v interface{}, lastModified time.Time) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(statsMaxAge.Seconds())))
+	if !lastModified.IsZero() {
+		lastModified = lastModified.UTC().Truncate(time.Second)
+		header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// notModified applies the conditional request headers, If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.After(since)
+	}
+
+	return false
+}
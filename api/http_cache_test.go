@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	const etag = `"abc"`
+	modified := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name         string
+		method       string
+		headers      map[string]string
+		lastModified time.Time
+		want         bool
+	}{
+		{"no conditions", http.MethodGet, nil, modified, false},
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": `"abc"`}, modified, true},
+		{"other etag", http.MethodGet, map[string]string{"If-None-Match": `"def"`}, modified, false},
+		{"etag in a list", http.MethodGet, map[string]string{"If-None-Match": `"def", "abc"`}, modified, true},
+		{"weak validator", http.MethodGet, map[string]string{"If-None-Match": `W/"abc"`}, modified, true},
+		{"weak validator of another etag", http.MethodGet, map[string]string{"If-None-Match": `W/"def"`}, modified, false},
+		{"unquoted etag", http.MethodGet, map[string]string{"If-None-Match": `abc`}, modified, false},
+		{"wildcard", http.MethodGet, map[string]string{"If-None-Match": `*`}, modified, true},
+		{"head", http.MethodHead, map[string]string{"If-None-Match": `"abc"`}, modified, true},
+		{"post", http.MethodPost, map[string]string{"If-None-Match": `"abc"`}, modified, false},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": after}, modified, true},
+		{"modified at the same second", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, modified, true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": before}, modified, false},
+		{"invalid date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, modified, false},
+		{"no last modified", http.MethodGet, map[string]string{"If-Modified-Since": after}, time.Time{}, false},
+		{"etag mismatch wins over date", http.MethodGet, map[string]string{"If-None-Match": `"def"`, "If-Modified-Since": after}, modified, false},
+		{"etag match wins over date", http.MethodGet, map[string]string{"If-None-Match": `"abc"`, "If-Modified-Since": before}, modified, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/open/stats/totals", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			if got := notModified(r, etag, tt.lastModified); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCachableJSON(t *testing.T) {
+	modified := time.Date(2023, 4, 1, 12, 0, 0, 500, time.UTC)
+	value := map[string]int{"deals": 3}
+
+	w := httptest.NewRecorder()
+	writeCachableJSON(context.Background(), w, httptest.NewRequest(http.MethodGet, "/open/stats/totals", nil), value, modified)
+	if w.Code != http.StatusOK || w.Body.String() != `{"deals":3}` {
+		t.Fatalf("response = %d %q", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got := w.Header().Get("Last-Modified"); got != "Sat, 01 Apr 2023 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	for header, condition := range map[string]string{"If-None-Match": etag, "If-Modified-Since": w.Header().Get("Last-Modified")} {
+		r := httptest.NewRequest(http.MethodGet, "/open/stats/totals", nil)
+		r.Header.Set(header, condition)
+		w := httptest.NewRecorder()
+		writeCachableJSON(context.Background(), w, r, value, modified)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s response = %d %q, want 304 without a body", header, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("%s response ETag = %q, want %q", header, w.Header().Get("ETag"), etag)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	writeCachableJSON(context.Background(), w, httptest.NewRequest(http.MethodGet, "/open/stats/totals", nil), value, time.Time{})
+	if got := w.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("Last-Modified without a time = %q", got)
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/gin-gonic/gin"
+	"github.com/go-co-op/gocron"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultRefreshSchedule used for groups without REFRESH_SCHEDULE_<GROUP> when REFRESH_SCHEDULE is not set
+	defaultRefreshSchedule = "4h"
+
+	// ScheduleKindInterval the group is refreshed every interval, starting at startup
+	ScheduleKindInterval = "interval"
+
+	// ScheduleKindCron the group is refreshed at the times of a cron expression, in UTC
+	ScheduleKindCron = "cron"
+
+	// ScheduleKindOff the group is only refreshed on demand
+	ScheduleKindOff = "off"
+)
+
+var (
+	refreshScheduler *gocron.Scheduler
+
+	// refreshSchedules one per view group, in group order
+	refreshSchedules []*RefreshSchedule
+)
+
+// RefreshSchedule is the refresh schedule of a view group
+type RefreshSchedule struct {
+	sync.Mutex `json:"-"`
+
+	Group  string `json:"group"`
+	Kind   string `json:"kind"`
+	Spec   string `json:"spec"`
+	Jitter string `json:"jitter,omitempty"`
+
+	// Runs number of scheduled refreshes started
+	Runs int `json:"runs"`
+
+	// Skipped number of scheduled refreshes skipped because the previous refresh of the group was still running
+	Skipped int `json:"skipped"`
+
+	// Deferred number of scheduled refreshes left to another instance holding the refresh lock of the group
+	Deferred int `json:"deferred"`
+
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	NextRun *time.Time `json:"nextRun,omitempty"`
+
+	jitter time.Duration
+	job    *gocron.Job
+}
+
+func configGinRefreshSchedulesRouter(router gin.IRoutes) {
+	router.GET("/admin/schedules", ConverHttprouterToGin(GetRefreshSchedules))
+}
+
+// StartRefreshScheduler schedules the refresh of every view group. REFRESH_SCHEDULE_<GROUP> sets the schedule of a
+// group and REFRESH_SCHEDULE the default, either an interval such as 1h, a cron expression such as "0 2 * * *" or off.
+// REFRESH_JITTER_<GROUP> and REFRESH_JITTER delay each run by a random duration up to the given one.
+func StartRefreshScheduler() error {
+	s := gocron.NewScheduler(time.UTC)
+
+	var schedules []*RefreshSchedule
+	for _, group := range dao.ViewGroups() {
+		schedule, err := newRefreshSchedule(group)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, schedule)
+
+		switch schedule.Kind {
+		case ScheduleKindOff:
+			continue
+		case ScheduleKindInterval:
+			// Every starts the job immediately and then runs at the specified interval
+			s.Every(schedule.Spec)
+		case ScheduleKindCron:
+			s.Cron(schedule.Spec)
+		}
+
+		job, err := s.Tag(group).Do(schedule.run)
+		if err != nil {
+			return fmt.Errorf("invalid refresh schedule %q for %s: %w", schedule.Spec, group, err)
+		}
+		schedule.job = job
+	}
+
+	refreshScheduler = s
+	refreshSchedules = schedules
+	s.StartAsync()
+	return nil
+}
+
+func newRefreshSchedule(group string) (*RefreshSchedule, error) {
+	key := strings.ToUpper(group)
+
+	spec := defaultRefreshSchedule
+	if viper.IsSet("REFRESH_SCHEDULE_" + key) {
+		spec = viper.GetString("REFRESH_SCHEDULE_" + key)
+	} else if viper.IsSet("REFRESH_SCHEDULE") {
+		spec = viper.GetString("REFRESH_SCHEDULE")
+	}
+	spec = strings.TrimSpace(spec)
+
+	schedule := &RefreshSchedule{Group: group, Spec: spec}
+	if spec == "" || strings.EqualFold(spec, ScheduleKindOff) {
+		schedule.Kind = ScheduleKindOff
+	} else if interval, err := time.ParseDuration(spec); err == nil {
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid refresh schedule %q for %s, the interval must be positive", spec, group)
+		}
+		schedule.Kind = ScheduleKindInterval
+	} else {
+		schedule.Kind = ScheduleKindCron
+	}
+
+	jitterKey := "REFRESH_JITTER"
+	if viper.IsSet("REFRESH_JITTER_" + key) {
+		jitterKey = "REFRESH_JITTER_" + key
+	}
+	if jitter := viper.GetString(jitterKey); jitter != "" {
+		d, err := time.ParseDuration(jitter)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %q, expected a duration such as 5m", jitterKey, jitter)
+		}
+		schedule.jitter = d
+		schedule.Jitter = d.String()
+	}
+
+	return schedule, nil
+}
+
+// run is the scheduled job of the group, it skips the run while a refresh of the group is in progress
+func (s *RefreshSchedule) run() {
+	if s.jitter > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(s.jitter))))
+	}
+
+	if dao.RunningRefreshJob(s.Group) != nil {
+		s.Lock()
+		s.Skipped++
+		s.Unlock()
+		log.Printf("skipping scheduled refresh of %s, the previous refresh is still running", s.Group)
+		return
+	}
+
+	now := time.Now().UTC()
+	s.Lock()
+	s.Runs++
+	s.LastRun = &now
+	s.Unlock()
+
+	fmt.Println("Refresh " + s.Group + " views")
+	job, err := dao.RefreshView(s.Group, dao.RefreshTriggerScheduler)
+	if errors.Is(err, dao.ErrRefreshDeferred) {
+		s.Lock()
+		s.Deferred++
+		s.Unlock()
+		log.Println(err)
+	} else if err != nil {
+		log.Println(err)
+	} else if job.Failed > 0 {
+		log.Printf("refresh job %s of %s finished %s, %d of %d statements failed", job.JobID, job.ViewName, job.Status, job.Failed, job.Statements)
+	}
+}
+
+// snapshot copies the schedule for rendering
+func (s *RefreshSchedule) snapshot() *RefreshSchedule {
+	s.Lock()
+	defer s.Unlock()
+
+	snapshot := &RefreshSchedule{
+		Group:    s.Group,
+		Kind:     s.Kind,
+		Spec:     s.Spec,
+		Jitter:   s.Jitter,
+		Runs:     s.Runs,
+		Skipped:  s.Skipped,
+		Deferred: s.Deferred,
+		LastRun:  s.LastRun,
+	}
+	if s.job != nil {
+		if next := s.job.NextRun(); !next.IsZero() {
+			snapshot.NextRun = &next
+		}
+	}
+	return snapshot
+}
+
+// GetRefreshSchedules lists the refresh schedule of every view group
+// @Summary List view refresh schedules
+// @Tags Views
+// @Produce  json
+// @Success 200 {array} api.RefreshSchedule
+// @Router /admin/schedules [get]
+func GetRefreshSchedules(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, "schedules", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	schedules := make([]*RefreshSchedule, 0, len(refreshSchedules))
+	for _, schedule := range refreshSchedules {
+		schedules = append(schedules, schedule.snapshot())
+	}
+	writeJSON(ctx, w, schedules)
+}
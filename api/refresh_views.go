@@ -1,182 +1,136 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
+
 	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/application-research/delta-metrics-rest/model"
 	"github.com/gin-gonic/gin"
 	"github.com/julienschmidt/httprouter"
-	"github.com/spf13/viper"
-	"net/http"
-	"strings"
 )
 
-const viewTypeRefreshGlobalStats = "global_stats"
-const viewTypeRefreshAllTableViews = "all_table_views"
-
-// write a temporary storage to track view refreshes
+const defaultRefreshHistory = 20
 
 func configGinRefreshViewsRouter(router gin.IRoutes) {
+	router.GET("/admin/views", ConverHttprouterToGin(GetViews))
 	router.GET("/admin/views/refresh/:view_name", ConverHttprouterToGin(RefreshView))
 	router.GET("/admin/views/refresh/status/:view_name", ConverHttprouterToGin(GetRefreshViewStatus))
 }
 
 type RefreshViewResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Job     *dao.RefreshJob `json:"job,omitempty"`
 }
 
-func GetRefreshViewStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+// RefreshViewStatus is the refresh state of a view
+type RefreshViewStatus struct {
+	ViewName string            `json:"viewName"`
+	Running  bool              `json:"running"`
+	Latest   *dao.RefreshJob   `json:"latest,omitempty"`
+	History  []*dao.RefreshJob `json:"history"`
+}
 
-	// use the tracker to return the result
+// GetViews lists the registered materialized views and their refresh groups
+// @Summary List materialized views
+// @Tags Views
+// @Produce  json
+// @Success 200 {array} dao.MaterializedView
+// @Router /admin/views [get]
+func GetViews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
-	viewName := ps.ByName("view_name")
-	if viewName == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		writeJSON(ctx, w, "view_name required")
-		return
-	}
-
-	// check if the view is being refreshed
-	if dao.ViewRefreshes[viewName] {
-		w.WriteHeader(http.StatusAccepted)
-		writeJSON(ctx, w,
-			struct {
-				Message string `json:"message"`
-			}{
-				Message: "refresh in progress for view " + viewName,
-			})
-		return
-	} else {
-		w.WriteHeader(http.StatusOK)
-		writeJSON(ctx, w, "no refresh in progress for view "+viewName)
+	if err := ValidateRequest(ctx, r, "views", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	writeJSON(ctx, w, dao.Views)
 }
 
-func RefreshView(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-
-	fmt.Println("refreshing view")
+// GetRefreshViewStatus returns the latest refresh of a view and its refresh history
+// @Summary Refresh status of a view
+// @Tags Views
+// @Produce  json
+// @Param  view_name path string true "view group or view name"
+// @Param  limit query int false "number of past runs to return, default 20, max 100"
+// @Success 200 {object} api.RefreshViewStatus
+// @Failure 400 {object} api.HTTPError
+// @Router /admin/views/refresh/status/{view_name} [get]
+func GetRefreshViewStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
-	authParts := strings.Split(r.Header.Get("Authorization"), " ")
-	if len(authParts) != 2 {
-		w.WriteHeader(http.StatusUnauthorized)
-		writeJSON(ctx, w, struct {
-			Message string `json:"message"`
-		}{
-			Message: "invalid authorization header",
-		})
+	viewName := ps.ByName("view_name")
+	if !dao.IsRefreshableView(viewName) {
+		returnError(ctx, w, r, fmt.Errorf("%w: unknown view %s", dao.ErrBadParams, viewName))
 		return
 	}
 
-	authSvcApi := viper.Get("AUTH_SVC_API").(string)
-	if authSvcApi == "" {
-		w.WriteHeader(http.StatusUnauthorized)
-		writeJSON(ctx, w, "AUTH_SVC_API not set")
+	if err := ValidateRequest(ctx, r, "views", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
-	response, err := http.Post(
-		authSvcApi+"/check-api-key",
-		"application/json",
-		strings.NewReader(fmt.Sprintf(`{"token": "%s"}`, authParts[1])),
-	)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(ctx, w, err.Error())
-		return
+	limit := defaultRefreshHistory
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			returnError(ctx, w, r, fmt.Errorf("%w: limit must be a positive number", dao.ErrBadParams))
+			return
+		}
 	}
 
-	authResp, err := GetAuthResponse(response)
+	history, err := dao.GetRefreshJobHistory(viewName, limit)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		writeJSON(ctx, w, err.Error())
+		returnError(ctx, w, r, err)
 		return
 	}
 
-	if authResp.Result.Validated == false {
-		w.WriteHeader(http.StatusUnauthorized)
-		writeJSON(ctx, w,
-			struct {
-				Message string `json:"message"`
-			}{
-				Message: "invalid api key",
-			})
-		return
+	status := &RefreshViewStatus{ViewName: viewName, History: history}
+	if running := dao.RunningRefreshJob(viewName); running != nil {
+		status.Running = true
+		status.Latest = running
+	} else if len(history) > 0 {
+		status.Latest = history[0]
 	}
 
-	// get query params
-	viewName := ps.ByName("view_name")
+	writeJSON(ctx, w, status)
+}
 
-	if viewName == viewTypeRefreshGlobalStats {
-		go func() {
-			_, err := dao.RefreshGlobalStatsView(viewName)
-			if err != nil {
-				returnError(ctx, w, r, err)
-				return
-			}
-		}()
-		// add tracking
-		dao.ViewRefreshes[viewName] = true
+// RefreshView starts a refresh of a view in the background
+// @Summary Refresh a view
+// @Tags Views
+// @Produce  json
+// @Param  view_name path string true "view group or view name"
+// @Success 202 {object} api.RefreshViewResponse
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError
+// @Router /admin/views/refresh/{view_name} [get]
+func RefreshView(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, "views", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
 	}
 
-	if viewName == viewTypeRefreshAllTableViews {
-		go func() {
-			_, err := dao.RefreshGlobalAllTableView(viewName)
-			if err != nil {
-				returnError(ctx, w, r, err)
-				return
-			}
-		}()
-		dao.ViewRefreshes[viewName] = true
+	viewName := ps.ByName("view_name")
+	if !dao.IsRefreshableView(viewName) {
+		returnError(ctx, w, r, fmt.Errorf("%w: unknown view %s", dao.ErrBadParams, viewName))
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	writeJSON(ctx, w,
-		struct {
-			Message string `json:"message"`
-		}{
-			Message: "Refresh view request received. Please wait for a few minutes for the view to be refreshed.",
-		},
-	)
-	return
-}
-
-type AuthResponse struct {
-	User struct {
-		Username string `json:"username"`
-		Perm     int    `json:"perm"`
-		Flags    int    `json:"flags"`
-	} `json:"user"`
-	Result struct {
-		Validated bool   `json:"validated"`
-		Details   string `json:"details"`
-	} `json:"result"`
-}
-
-func GetAuthResponse(resp *http.Response) (AuthResponse, error) {
-	jsonBody := AuthResponse{}
-	err := json.NewDecoder(resp.Body).Decode(&jsonBody)
+	job, err := dao.StartViewRefresh(viewName, dao.RefreshTriggerAdmin)
 	if err != nil {
-		return AuthResponse{
-			User: struct {
-				Username string `json:"username"`
-				Perm     int    `json:"perm"`
-				Flags    int    `json:"flags"`
-			}{
-				Username: "",
-				Perm:     0,
-				Flags:    0,
-			},
-			Result: struct {
-				Validated bool   `json:"validated"`
-				Details   string `json:"details"`
-			}{
-				Validated: false,
-				Details:   "empty json body",
-			},
-		}, nil
+		returnError(ctx, w, r, err)
+		return
 	}
-	return jsonBody, nil
+
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(ctx, w, &RefreshViewResponse{
+		Success: true,
+		Message: "Refresh view request received. Please wait for a few minutes for the view to be refreshed.",
+		Job:     job,
+	})
 }
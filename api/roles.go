@@ -0,0 +1,29 @@
+package api
+
+import "context"
+
+// Role of the caller of a request
+type Role string
+
+const (
+	// RoleAdmin callers can read sensitive columns
+	RoleAdmin = Role("admin")
+)
+
+type roleContextKey struct{}
+
+// WithRole returns a copy of ctx carrying the caller role, it is meant to be used from a ContextInitializer
+func WithRole(ctx context.Context, role Role) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+// RoleFromContext returns the caller role set with WithRole, or an empty role
+func RoleFromContext(ctx context.Context) Role {
+	role, _ := ctx.Value(roleContextKey{}).(Role)
+	return role
+}
+
+// canReadSensitive reports whether the caller may see sensitive column values
+func canReadSensitive(ctx context.Context) bool {
+	return RoleFromContext(ctx) == RoleAdmin
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	_ "github.com/satori/go.uuid"
 	"io/ioutil"
@@ -26,21 +27,28 @@ var (
 // CrudAPI describes requests available for tables in the database
 type CrudAPI struct {
 	Name            string           `json:"name"`
-	CreateURL       string           `json:"createUrl"`
-	RetrieveOneURL  string           `json:"retrieveOneUrl"`
-	RetrieveManyURL string           `json:"retrieveManyUrl"`
-	UpdateURL       string           `json:"updateUrl"`
-	DeleteURL       string           `json:"deleteUrl"`
+	Mode            CrudMode         `json:"mode,omitempty"`
+	CreateURL       string           `json:"createUrl,omitempty"`
+	RetrieveOneURL  string           `json:"retrieveOneUrl,omitempty"`
+	RetrieveManyURL string           `json:"retrieveManyUrl,omitempty"`
+	UpdateURL       string           `json:"updateUrl,omitempty"`
+	DeleteURL       string           `json:"deleteUrl,omitempty"`
 	FetchDDLURL     string           `json:"fetchDdlUrl"`
 	TableInfo       *model.TableInfo `json:"tableInfo"`
+
+	// mount mounts the generated routes of the table allowed by a mode
+	mount func(router gin.IRoutes, mode CrudMode)
 }
 
 // PagedResults results for pages GetAll results.
+// TotalRecords is -1 when the count was skipped, NextCursor is only set in cursor mode when there are more records.
 type PagedResults struct {
-	Page         int64       `json:"page"`
-	PageSize     int64       `json:"pageSize"`
-	Data         interface{} `json:"data"`
-	TotalRecords int         `json:"totalRecords"`
+	Page           int64       `json:"page"`
+	PageSize       int64       `json:"pageSize"`
+	Data           interface{} `json:"data"`
+	TotalRecords   int         `json:"totalRecords"`
+	TotalEstimated bool        `json:"totalEstimated,omitempty"`
+	NextCursor     string      `json:"nextCursor,omitempty"`
 }
 
 // HTTPError example
@@ -50,13 +58,18 @@ type HTTPError struct {
 }
 
 // ConfigGinRouter configure gin router
-func ConfigGinRouter(router gin.IRoutes) {
+func ConfigGinRouter(router gin.IRoutes) error {
 	configGinStatisticsRouter(router)
 	configGinRefreshViewsRouter(router)
+	configGinRefreshSchedulesRouter(router)
+	configGinCacheRouter(router)
 	configGinStatisticsTimeSeriesRouter(router)
+	if err := configGinCrudRouter(router); err != nil {
+		return err
+	}
 	router.GET("/ddl/:argID", ConverHttprouterToGin(GetDdl))
 	router.GET("/ddl", ConverHttprouterToGin(GetDdlEndpoints))
-	return
+	return nil
 }
 
 // ConverHttprouterToGin wrap httprouter.Handle to gin.HandlerFunc
@@ -108,6 +121,75 @@ func readInt(r *http.Request, param string, v int64) (int64, error) {
 	return strconv.ParseInt(p, 10, 64)
 }
 
+// listQueryParams are the query parameters of list endpoints that are not column filters
+var listQueryParams = []string{"page", "pagesize", "order", "sort", "cursor", "count", "fields"}
+
+// readListQuery reads the paging, sort order, column filter, count and fields parameters of a list request for table
+func readListQuery(ctx context.Context, r *http.Request, table string) (*dao.ListQuery, error) {
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		return nil, dao.ErrBadParams
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		return nil, dao.ErrBadParams
+	}
+
+	tableInfo, ok := model.GetTableInfo(table)
+	if !ok {
+		return nil, fmt.Errorf("unable to find table: %s", table)
+	}
+	tableInfo = visibleTableInfo(ctx, tableInfo)
+
+	filters, err := dao.ParseFilters(tableInfo, r.URL.Query(), listQueryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := dao.ParseSort(tableInfo, r.FormValue("order"), r.FormValue("sort"))
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := dao.ParseCountMode(r.FormValue("count"))
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := dao.ParseFields(tableInfo, r.FormValue("fields"))
+	if err != nil {
+		return nil, err
+	}
+
+	query := &dao.ListQuery{
+		Page:     page,
+		PageSize: pagesize,
+		Sort:     sort,
+		Filters:  filters,
+		Count:    count,
+		Fields:   fields,
+	}
+
+	// a cursor parameter, even empty, switches the request to keyset pagination
+	if _, ok := r.URL.Query()["cursor"]; ok {
+		if page > 0 {
+			return nil, fmt.Errorf("%w: use either page or cursor, not both", dao.ErrBadParams)
+		}
+
+		if r.FormValue("order") == "" && r.FormValue("sort") == "" {
+			query.Sort = dao.DefaultKeysetSort(tableInfo)
+		}
+
+		if query.After, err = dao.ParseCursor(tableInfo, query.Sort, r.FormValue("cursor")); err != nil {
+			return nil, err
+		}
+		query.UseCursor = true
+	}
+
+	return query, nil
+}
+
 func writeJSON(ctx context.Context, w http.ResponseWriter, v interface{}) {
 	data, _ := json.Marshal(v)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -133,18 +215,26 @@ func readJSON(r *http.Request, v interface{}) error {
 
 func returnError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
 	status := 0
-	switch err {
-	case dao.ErrNotFound:
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrAuthUnavailable):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, dao.ErrRefreshInProgress), errors.Is(err, dao.ErrRefreshDeferred):
+		status = http.StatusConflict
+	case errors.Is(err, dao.ErrNotFound):
 		status = http.StatusBadRequest
-	case dao.ErrUnableToMarshalJSON:
+	case errors.Is(err, dao.ErrUnableToMarshalJSON):
 		status = http.StatusBadRequest
-	case dao.ErrUpdateFailed:
+	case errors.Is(err, dao.ErrUpdateFailed):
 		status = http.StatusBadRequest
-	case dao.ErrInsertFailed:
+	case errors.Is(err, dao.ErrInsertFailed):
 		status = http.StatusBadRequest
-	case dao.ErrDeleteFailed:
+	case errors.Is(err, dao.ErrDeleteFailed):
 		status = http.StatusBadRequest
-	case dao.ErrBadParams:
+	case errors.Is(err, dao.ErrBadParams):
 		status = http.StatusBadRequest
 	default:
 		status = http.StatusBadRequest
@@ -270,13 +360,13 @@ func GetDdl(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	record, ok := crudEndpoints[argID]
+	record, ok := mountedCrudEndpoints[argID]
 	if !ok {
 		returnError(ctx, w, r, fmt.Errorf("unable to find table: %s", argID))
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeJSON(ctx, w, renderCrudAPI(ctx, record))
 }
 
 // GetDdlEndpoints is a function to get a list of ddl endpoints available for tables in the estuary database
@@ -296,7 +386,12 @@ func GetDdlEndpoints(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	writeJSON(ctx, w, crudEndpoints)
+	endpoints := make(map[string]*CrudAPI, len(mountedCrudEndpoints))
+	for name, endpoint := range mountedCrudEndpoints {
+		endpoints[name] = renderCrudAPI(ctx, endpoint)
+	}
+
+	writeJSON(ctx, w, endpoints)
 }
 
 func init() {
@@ -312,6 +407,7 @@ func init() {
 		UpdateURL:       "/contentdeallogs",
 		DeleteURL:       "/contentdeallogs",
 		FetchDDLURL:     "/ddl/content_deal_logs",
+		mount:           configGinContentDealLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_deal_logs")
@@ -325,6 +421,7 @@ func init() {
 		UpdateURL:       "/contentdealproposallogs",
 		DeleteURL:       "/contentdealproposallogs",
 		FetchDDLURL:     "/ddl/content_deal_proposal_logs",
+		mount:           configGinContentDealProposalLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_deal_proposal_logs")
@@ -338,6 +435,7 @@ func init() {
 		UpdateURL:       "/contentdealproposalparameterslogs",
 		DeleteURL:       "/contentdealproposalparameterslogs",
 		FetchDDLURL:     "/ddl/content_deal_proposal_parameters_logs",
+		mount:           configGinContentDealProposalParametersLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_deal_proposal_parameters_logs")
@@ -351,6 +449,7 @@ func init() {
 		UpdateURL:       "/contentlogs",
 		DeleteURL:       "/contentlogs",
 		FetchDDLURL:     "/ddl/content_logs",
+		mount:           configGinContentLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_logs")
@@ -364,6 +463,7 @@ func init() {
 		UpdateURL:       "/contentminerlogs",
 		DeleteURL:       "/contentminerlogs",
 		FetchDDLURL:     "/ddl/content_miner_logs",
+		mount:           configGinContentMinerLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_miner_logs")
@@ -377,6 +477,7 @@ func init() {
 		UpdateURL:       "/contentwalletlogs",
 		DeleteURL:       "/contentwalletlogs",
 		FetchDDLURL:     "/ddl/content_wallet_logs",
+		mount:           configGinContentWalletLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("content_wallet_logs")
@@ -390,6 +491,7 @@ func init() {
 		UpdateURL:       "/deltanodegeolocations",
 		DeleteURL:       "/deltanodegeolocations",
 		FetchDDLURL:     "/ddl/delta_node_geo_locations",
+		mount:           configGinDeltaNodeGeoLocationsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("delta_node_geo_locations")
@@ -403,6 +505,7 @@ func init() {
 		UpdateURL:       "/deltastartuplogs",
 		DeleteURL:       "/deltastartuplogs",
 		FetchDDLURL:     "/ddl/delta_startup_logs",
+		mount:           configGinDeltaStartupLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("delta_startup_logs")
@@ -416,6 +519,7 @@ func init() {
 		UpdateURL:       "/instancemetalogs",
 		DeleteURL:       "/instancemetalogs",
 		FetchDDLURL:     "/ddl/instance_meta_logs",
+		mount:           configGinInstanceMetaLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("instance_meta_logs")
@@ -429,6 +533,7 @@ func init() {
 		UpdateURL:       "/logevents",
 		DeleteURL:       "/logevents",
 		FetchDDLURL:     "/ddl/log_events",
+		mount:           configGinLogEventsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("log_events")
@@ -442,6 +547,7 @@ func init() {
 		UpdateURL:       "/piececommitmentlogs",
 		DeleteURL:       "/piececommitmentlogs",
 		FetchDDLURL:     "/ddl/piece_commitment_logs",
+		mount:           configGinPieceCommitmentLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("piece_commitment_logs")
@@ -455,6 +561,7 @@ func init() {
 		UpdateURL:       "/walletlogs",
 		DeleteURL:       "/walletlogs",
 		FetchDDLURL:     "/ddl/wallet_logs",
+		mount:           configGinWalletLogsRouter,
 	}
 
 	tmp.TableInfo, _ = model.GetTableInfo("wallet_logs")
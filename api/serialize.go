@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/spf13/viper"
+)
+
+const (
+	// redactedValue replaces sensitive column values in SENSITIVE_MODE=mask
+	redactedValue = "[redacted]"
+
+	sensitiveModeMask = "mask"
+	sensitiveModeDrop = "drop"
+)
+
+// readFields reads the fields parameter of a read request for table
+func readFields(ctx context.Context, r *http.Request, table string) ([]string, error) {
+	tableInfo, ok := model.GetTableInfo(table)
+	if !ok {
+		return nil, fmt.Errorf("unable to find table: %s", table)
+	}
+
+	return dao.ParseFields(visibleTableInfo(ctx, tableInfo), r.FormValue("fields"))
+}
+
+// visibleTableInfo returns the table info with the sensitive columns removed when the caller is not an admin,
+// it is used to parse request parameters so that callers cannot select, filter or sort on columns they cannot read.
+func visibleTableInfo(ctx context.Context, tableInfo *model.TableInfo) *model.TableInfo {
+	if canReadSensitive(ctx) {
+		return tableInfo
+	}
+
+	visible := &model.TableInfo{Name: tableInfo.Name}
+	for _, column := range tableInfo.Columns {
+		if !column.Sensitive {
+			visible.Columns = append(visible.Columns, column)
+		}
+	}
+	return visible
+}
+
+// renderRecords prepares a record, or a slice of records, of table for serialisation.
+// Records are reduced to a map keyed like the full record when fields is set, or when they carry sensitive columns
+// the caller cannot read; these are masked or dropped depending on SENSITIVE_MODE.
+func renderRecords(ctx context.Context, table string, v interface{}, fields []string) interface{} {
+	tableInfo, ok := model.GetTableInfo(table)
+	if !ok {
+		return v
+	}
+
+	redact := !canReadSensitive(ctx) && hasSensitiveColumns(tableInfo)
+	if len(fields) == 0 && !redact {
+		return v
+	}
+
+	if len(fields) == 0 {
+		for _, column := range tableInfo.Columns {
+			fields = append(fields, column.Name)
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return renderRecord(tableInfo, rv, fields, redact)
+	}
+
+	records := make([]map[string]interface{}, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		records[i] = renderRecord(tableInfo, rv.Index(i), fields, redact)
+	}
+	return records
+}
+
+func renderRecord(tableInfo *model.TableInfo, rv reflect.Value, fields []string, redact bool) map[string]interface{} {
+	rv = reflect.Indirect(rv)
+	drop := viper.GetString("SENSITIVE_MODE") == sensitiveModeDrop
+	record := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		for _, column := range tableInfo.Columns {
+			if column.Name != name {
+				continue
+			}
+
+			value := rv.FieldByName(column.GoFieldName).Interface()
+			if redact && column.Sensitive {
+				if drop {
+					break
+				}
+				value = redactedValue
+			}
+
+			record[column.GoFieldName] = value
+			break
+		}
+	}
+	return record
+}
+
+// renderCrudAPI prepares a /ddl entry for serialisation, in SENSITIVE_MODE=drop the sensitive columns are hidden from non admins
+func renderCrudAPI(ctx context.Context, endpoint *CrudAPI) *CrudAPI {
+	if endpoint.TableInfo == nil || viper.GetString("SENSITIVE_MODE") != sensitiveModeDrop {
+		return endpoint
+	}
+
+	rendered := *endpoint
+	rendered.TableInfo = visibleTableInfo(ctx, endpoint.TableInfo)
+	return &rendered
+}
+
+func hasSensitiveColumns(tableInfo *model.TableInfo) bool {
+	for _, column := range tableInfo.Columns {
+		if column.Sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigureSensitiveColumns marks the columns listed in SENSITIVE_COLUMNS as sensitive, in addition to model.DefaultSensitiveColumns
+func ConfigureSensitiveColumns() error {
+	switch mode := viper.GetString("SENSITIVE_MODE"); mode {
+	case "", sensitiveModeMask, sensitiveModeDrop:
+	default:
+		return fmt.Errorf("invalid SENSITIVE_MODE %q, expected mask or drop", mode)
+	}
+
+	return model.MarkSensitiveColumns(strings.Split(viper.GetString("SENSITIVE_COLUMNS"), ","))
+}
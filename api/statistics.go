@@ -5,20 +5,24 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/julienschmidt/httprouter"
 	"net/http"
+	"time"
 )
 
 func configGinStatisticsRouter(router gin.IRoutes) {
 	router.GET("/open/stats/totals/info", ConverHttprouterToGin(GetOpenTotalInfoStats))
 	router.GET("/open/stats/list/sps", ConverHttprouterToGin(GetAllSps))
+	router.GET("/open/stats/sps/:miner", ConverHttprouterToGin(GetSPProfile))
 	router.GET("/open/stats/list/wallet/addrs", ConverHttprouterToGin(GetWalletsAddrs))
 	router.GET("/open/stats/instance/ips", ConverHttprouterToGin(GetDeltaIps))
+	router.GET("/open/stats/nodes", ConverHttprouterToGin(GetDeltaNodes))
+	router.GET("/open/stats/nodes/:delta_node_uuid", ConverHttprouterToGin(GetDeltaNodeProfile))
+	router.GET("/open/stats/leaderboard/sps", ConverHttprouterToGin(GetSPLeaderboard))
+	router.GET("/open/stats/leaderboard/nodes", ConverHttprouterToGin(GetNodeLeaderboard))
 
-	// open stats
-	//router.GET("/open/stats/onboarded/deals/by-sp/:sp_id", ConverHttprouterToGin(GetDeltaIps))
-	// /open/stats/onboarded/deals/by-username/:username
-	// /open/stats/onboarded/deals/by-sp/:sp_id
-	// /open/stats/onboarded/deals/by-delta-uuid/:delta_uuid
-	// /open/stats/onboarded/deals/by-key/ (post)
+	router.GET("/open/stats/onboarded/deals/by-sp/:sp_id", ConverHttprouterToGin(GetOnboardedDealsBySP))
+	router.GET("/open/stats/onboarded/deals/by-delta-uuid/:delta_uuid", ConverHttprouterToGin(GetOnboardedDealsByDeltaNode))
+	router.POST("/open/stats/onboarded/deals/by-key", ConverHttprouterToGin(GetOnboardedDealsByKey))
+	// no by-username route, the view only records api keys and the auth service cannot list the keys of a user
 }
 
 func GetOpenTotalInfoStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -29,7 +33,7 @@ func GetOpenTotalInfoStats(w http.ResponseWriter, r *http.Request, ps httprouter
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeCachableJSON(ctx, w, r, record, record.ComputedAt)
 }
 
 func GetAllSps(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -40,7 +44,8 @@ func GetAllSps(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	// read from the log tables, not from views, so only the ETag tells whether it changed
+	writeCachableJSON(ctx, w, r, record, time.Time{})
 }
 
 func GetWalletsAddrs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -51,7 +56,7 @@ func GetWalletsAddrs(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeCachableJSON(ctx, w, r, record, time.Time{})
 }
 
 func GetDeltaIps(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -62,5 +67,5 @@ func GetDeltaIps(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeCachableJSON(ctx, w, r, record, time.Time{})
 }
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetSPLeaderboard is a function to get the top storage providers
+// @Summary Top storage providers
+// @Tags Stats
+// @Produce  json
+// @Param  limit query int false "number of entries, default 10, max 100"
+// @Param  window query string false "24h, 7d, 30d or all, default all"
+// @Param  sort_by query string false "bytes, deals or success_rate, default bytes"
+// @Success 200 {array} dao.SPRanking
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/leaderboard/sps [get]
+func GetSPLeaderboard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	query, err := dao.ParseLeaderboardQuery(r.URL.Query())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetSPLeaderboard(query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	lastModified, _ := dao.LastViewRefresh(dao.ViewGroupDashboard)
+	writeCachableJSON(ctx, w, r, record, lastModified)
+}
+
+// GetNodeLeaderboard is a function to get the top delta nodes
+// @Summary Top delta nodes
+// @Tags Stats
+// @Produce  json
+// @Param  limit query int false "number of entries, default 10, max 100"
+// @Param  window query string false "24h, 7d, 30d or all, default all"
+// @Param  sort_by query string false "bytes, deals or success_rate, default bytes"
+// @Success 200 {array} dao.NodeRanking
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/leaderboard/nodes [get]
+func GetNodeLeaderboard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	query, err := dao.ParseLeaderboardQuery(r.URL.Query())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetNodeLeaderboard(query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	lastModified, _ := dao.LastViewRefresh(dao.ViewGroupDashboard)
+	writeCachableJSON(ctx, w, r, record, lastModified)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetDeltaNodes is a function to get the inventory of delta nodes
+// @Summary Delta node inventory
+// @Tags Stats
+// @Produce  json
+// @Success 200 {array} dao.DeltaNode
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/nodes [get]
+func GetDeltaNodes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	record, err := dao.GetDeltaNodes()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCachableJSON(ctx, w, r, record, time.Time{})
+}
+
+// GetDeltaNodeProfile is a function to get a delta node with its startup history
+// @Summary Delta node profile
+// @Tags Stats
+// @Produce  json
+// @Param  delta_node_uuid path string true "delta node uuid"
+// @Success 200 {object} dao.DeltaNodeProfile
+// @Failure 400 {object} api.HTTPError "ErrNotFound, no log mentions the delta node"
+// @Router /open/stats/nodes/{delta_node_uuid} [get]
+func GetDeltaNodeProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	record, err := dao.GetDeltaNodeProfile(ps.ByName("delta_node_uuid"))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCachableJSON(ctx, w, r, record, time.Time{})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OnboardedDealsByKeyRequest is the body of the by-key lookup, the key is sent in the body so it stays out of urls and access logs
+type OnboardedDealsByKeyRequest struct {
+	Key string `json:"key"`
+}
+
+// GetOnboardedDealsBySP is a function to get the onboarded deals of a storage provider
+// @Summary Onboarded deals of a storage provider
+// @Tags Stats
+// @Produce  json
+// @Param  sp_id path string true "storage provider, e.g. f01000"
+// @Param  page query int false "page requested, from 1 (defaults to the first page)"
+// @Param  pagesize query int false "number of deals in a page (defaults to 20, max 100)"
+// @Success 200 {object} dao.OnboardedDeals
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/onboarded/deals/by-sp/{sp_id} [get]
+func GetOnboardedDealsBySP(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	getOnboardedDeals(ctx, w, r, dao.OnboardedDealsBySP, ps.ByName("sp_id"))
+}
+
+// GetOnboardedDealsByDeltaNode is a function to get the onboarded deals of a delta node
+// @Summary Onboarded deals of a delta node
+// @Tags Stats
+// @Produce  json
+// @Param  delta_uuid path string true "delta node uuid"
+// @Param  page query int false "page requested, from 1 (defaults to the first page)"
+// @Param  pagesize query int false "number of deals in a page (defaults to 20, max 100)"
+// @Success 200 {object} dao.OnboardedDeals
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/onboarded/deals/by-delta-uuid/{delta_uuid} [get]
+func GetOnboardedDealsByDeltaNode(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	getOnboardedDeals(ctx, w, r, dao.OnboardedDealsByDeltaNode, ps.ByName("delta_uuid"))
+}
+
+// GetOnboardedDealsByKey is a function to get the onboarded deals requested with an api key
+// @Summary Onboarded deals of an api key
+// @Tags Stats
+// @Accept  json
+// @Produce  json
+// @Param  request body api.OnboardedDealsByKeyRequest true "api key"
+// @Param  page query int false "page requested, from 1 (defaults to the first page)"
+// @Param  pagesize query int false "number of deals in a page (defaults to 20, max 100)"
+// @Success 200 {object} dao.OnboardedDeals
+// @Failure 400 {object} api.HTTPError
+// @Router /open/stats/onboarded/deals/by-key [post]
+func GetOnboardedDealsByKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	request := &OnboardedDealsByKeyRequest{}
+	if err := readJSON(r, request); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	getOnboardedDeals(ctx, w, r, dao.OnboardedDealsByKey, request.Key)
+}
+
+func getOnboardedDeals(ctx context.Context, w http.ResponseWriter, r *http.Request, dimension, value string) {
+	page, err := readInt(r, "page", 0)
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	record, err := dao.GetOnboardedDeals(dimension, value, page, pagesize)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// deals of an api key are only returned to whoever holds the key, they must not be kept by shared caches
+	if dimension == dao.OnboardedDealsByKey {
+		writeJSON(ctx, w, record)
+		return
+	}
+
+	lastModified, _ := dao.LastViewRefresh(dao.ViewGroupOnboarded)
+	writeCachableJSON(ctx, w, r, record, lastModified)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetSPProfile is a function to get the profile of a storage provider
+// @Summary Storage provider profile
+// @Tags Stats
+// @Produce  json
+// @Param  miner path string true "storage provider, e.g. f01000"
+// @Success 200 {object} dao.SPProfile
+// @Failure 400 {object} api.HTTPError "ErrNotFound, no log mentions the storage provider"
+// @Router /open/stats/sps/{miner} [get]
+func GetSPProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	record, err := dao.GetSPProfile(ps.ByName("miner"))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCachableJSON(ctx, w, r, record, time.Time{})
+}
@@ -33,12 +33,17 @@ import (
 // TotalNumberOfUniqueDeltaNodes             int `json:"total_number_of_unique_delta_nodes,omitempty"`
 func configGinStatisticsTimeSeriesRouter(router gin.IRoutes) {
 	router.GET("/stats/deals-attempted", ConverHttprouterToGin(GetRangeOfDealsAttempted))
+	router.GET("/stats/timeseries", ConverHttprouterToGin(GetTimeSeriesMetrics))
+	router.GET("/stats/timeseries/:metric", ConverHttprouterToGin(GetTimeSeries))
 }
 
 func GetRangeOfDealsAttempted(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
-	from := r.URL.Query().Get("from")
-	to := r.URL.Query().Get("to")
+	from, to, err := dao.ParseTimeRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	record, err := dao.GetDealsAttemptedInRange(from, to)
 	if err != nil {
@@ -48,3 +53,42 @@ func GetRangeOfDealsAttempted(w http.ResponseWriter, r *http.Request, ps httprou
 
 	writeJSON(ctx, w, record)
 }
+
+// GetTimeSeriesMetrics is a function to list the metrics served as time series
+// @Summary List time series metrics
+// @Tags Stats
+// @Produce  json
+// @Success 200 {array} dao.TimeSeriesMetric
+// @Router /stats/timeseries [get]
+func GetTimeSeriesMetrics(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	writeJSON(ctx, w, dao.TimeSeriesMetrics)
+}
+
+// GetTimeSeries is a function to get a metric bucketed over time
+// @Summary Metric time series
+// @Tags Stats
+// @Produce  json
+// @Param  metric path string true "metric name, see /stats/timeseries"
+// @Param  from query string false "RFC3339 time or YYYY-MM-DD date, inclusive, default 30 days before to"
+// @Param  to query string false "RFC3339 time or YYYY-MM-DD date, exclusive, default the end of the current minute"
+// @Param  interval query string false "hour, day, week or month, default day"
+// @Success 200 {object} dao.TimeSeries
+// @Failure 400 {object} api.HTTPError
+// @Router /stats/timeseries/{metric} [get]
+func GetTimeSeries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	query, err := dao.ParseTimeSeriesQuery(r.URL.Query().Get("from"), r.URL.Query().Get("to"), r.URL.Query().Get("interval"))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTimeSeries(ps.ByName("metric"), query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCachableJSON(ctx, w, r, record, record.ComputedAt)
+}
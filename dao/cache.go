@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores json encoded values under string keys, each with its own ttl
+type Cache interface {
+	// Get decodes the value of key into dest, ok is false when the key is missing or expired
+	Get(key string, dest interface{}) (ok bool, err error)
+
+	// Set stores value under key, a ttl of 0 uses the default ttl of the cache
+	Set(key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes keys, missing keys are ignored
+	Delete(keys ...string) error
+
+	// Flush removes every key of the cache
+	Flush() error
+
+	// Keys lists the live keys
+	Keys() ([]string, error)
+
+	// Stats returns the counters of the cache
+	Stats() CacheStats
+}
+
+// CacheStats are the counters of a Cache
+type CacheStats struct {
+	Backend   string `json:"backend"`
+	Hits      int64  `json:"hits"`
+	Misses    int64  `json:"misses"`
+	Sets      int64  `json:"sets"`
+	Evictions int64  `json:"evictions"`
+	Errors    int64  `json:"errors"`
+
+	// Keys number of live keys, -1 when the backend cannot tell
+	Keys int64 `json:"keys"`
+
+	// Bytes size of the stored values, -1 when the backend cannot tell
+	Bytes int64 `json:"bytes"`
+
+	// MaxBytes byte budget of the cache, 0 when the backend manages its own memory
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// cacheCounters are the hit, miss and error counters shared by the Cache implementations
+type cacheCounters struct {
+	hits      int64
+	misses    int64
+	sets      int64
+	evictions int64
+	errors    int64
+}
+
+func (c *cacheCounters) hit()            { atomic.AddInt64(&c.hits, 1) }
+func (c *cacheCounters) miss()           { atomic.AddInt64(&c.misses, 1) }
+func (c *cacheCounters) set()            { atomic.AddInt64(&c.sets, 1) }
+func (c *cacheCounters) evicted(n int64) { atomic.AddInt64(&c.evictions, n) }
+func (c *cacheCounters) failed()         { atomic.AddInt64(&c.errors, 1) }
+
+func (c *cacheCounters) stats(backend string) CacheStats {
+	return CacheStats{
+		Backend:   backend,
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Sets:      atomic.LoadInt64(&c.sets),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Errors:    atomic.LoadInt64(&c.errors),
+	}
+}
+
+// CacheTTLs overrides the default ttl of individual keys
+var CacheTTLs = make(map[string]time.Duration)
+
+// ParseCacheTTLs parses a comma separated list of key=duration entries, e.g. statsTotal=10m,allSpsStats=1h
+func ParseCacheTTLs(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid cache ttl entry %q, expected key=duration", entry)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid cache ttl entry %q, expected key=duration", entry)
+		}
+		ttls[strings.TrimSpace(parts[0])] = ttl
+	}
+	return ttls, nil
+}
@@ -0,0 +1,169 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// CacheDuration time a cached value is served as fresh, CacheTTLs overrides it per key
+	CacheDuration = 4 * time.Hour
+
+	// CacheStaleWindow time a value is still served after it stopped being fresh, while a single background
+	// fill replaces it. 0 turns stale-while-revalidate off.
+	CacheStaleWindow time.Duration
+
+	// cacheFills de-duplicates concurrent fills of the same key
+	cacheFills = &flightGroup{calls: make(map[string]*flightCall)}
+)
+
+// cacheEnvelope is the cached form of a value filled by cacheFetch
+type cacheEnvelope struct {
+	Value      json.RawMessage `json:"value"`
+	FreshUntil time.Time       `json:"freshUntil"`
+}
+
+// cacheFetch decodes the cached value of key into dest, computing and caching it on a miss.
+// Concurrent misses of a key wait for a single compute. A stale value is returned right away while one
+// background compute replaces it. Cache errors are logged and fall back to compute.
+func cacheFetch(key string, dest interface{}, compute func() (interface{}, error)) error {
+	fill := func() ([]byte, error) {
+		return fillCache(key, compute)
+	}
+
+	var envelope cacheEnvelope
+	ok, err := Cacher.Get(key, &envelope)
+	if err != nil {
+		log.Printf("cache get %s failed, the error is '%v'", key, err)
+	}
+
+	if ok && err == nil {
+		if time.Now().After(envelope.FreshUntil) {
+			go revalidate(key, fill)
+		}
+		return json.Unmarshal(envelope.Value, dest)
+	}
+
+	value, err, _ := cacheFills.Do(key, fill)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(value, dest)
+}
+
+// fillCache computes the value of key and caches it, fresh for the ttl of the key and stale for CacheStaleWindow.
+// When a view of key is refreshed during the compute, the value may predate the refresh and is returned uncached.
+func fillCache(key string, compute func() (interface{}, error)) ([]byte, error) {
+	views := taggedViews(key)
+	generation := viewsGeneration(views)
+	result, err := compute()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := CacheDuration
+	if keyTTL, ok := CacheTTLs[key]; ok {
+		ttl = keyTTL
+	}
+	envelope := &cacheEnvelope{Value: value, FreshUntil: time.Now().Add(ttl)}
+	if viewsGeneration(views) != generation {
+		return value, nil
+	}
+	if err := Cacher.Set(key, envelope, ttl+CacheStaleWindow); err != nil {
+		log.Printf("cache set %s failed, the error is '%v'", key, err)
+	}
+
+	// a refresh completing between the check and the set may have evicted the key before it was set
+	if viewsGeneration(views) != generation {
+		if err := Cacher.Delete(key); err != nil {
+			log.Printf("cache delete %s failed, the error is '%v'", key, err)
+		}
+	}
+	return value, nil
+}
+
+// revalidate refills a stale key in the background, unless a fill of the key is already running
+func revalidate(key string, fill func() ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("background fill of cache key %s failed, the error is '%v'", key, r)
+		}
+	}()
+
+	if _, err, started := cacheFills.TryDo(key, fill); started && err != nil {
+		log.Printf("background fill of cache key %s failed, the error is '%v'", key, err)
+	}
+}
+
+// flightGroup runs at most one call per key at a time, callers of a key already running wait for its result
+type flightGroup struct {
+	sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// Do runs fn for key, or waits for the call of key already running and shares its result
+func (g *flightGroup) Do(key string, fn func() ([]byte, error)) (value []byte, err error, shared bool) {
+	g.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+
+	call := g.start(key)
+	g.Unlock()
+
+	g.run(key, call, fn)
+	return call.value, call.err, false
+}
+
+// TryDo runs fn for key unless a call of key is already running, started reports whether fn ran
+func (g *flightGroup) TryDo(key string, fn func() ([]byte, error)) (value []byte, err error, started bool) {
+	g.Lock()
+	if _, ok := g.calls[key]; ok {
+		g.Unlock()
+		return nil, nil, false
+	}
+
+	call := g.start(key)
+	g.Unlock()
+
+	g.run(key, call, fn)
+	return call.value, call.err, true
+}
+
+func (g *flightGroup) start(key string) *flightCall {
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call
+}
+
+// run calls fn and releases the waiters, a panic in fn is returned to every caller as an error
+func (g *flightGroup) run(key string, call *flightCall, fn func() ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("fill of cache key %s failed: %v", key, r)
+		}
+
+		g.Lock()
+		delete(g.calls, key)
+		g.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+}
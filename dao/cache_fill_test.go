@@ -0,0 +1,243 @@
+package dao
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useMemoryCache installs a fresh memory Cacher for the test and restores the cache settings afterwards
+func useMemoryCache(t *testing.T, staleWindow time.Duration) {
+	t.Helper()
+
+	cacher, duration, window, ttls := Cacher, CacheDuration, CacheStaleWindow, CacheTTLs
+	t.Cleanup(func() {
+		Cacher, CacheDuration, CacheStaleWindow, CacheTTLs = cacher, duration, window, ttls
+	})
+
+	Cacher = NewMemoryCache(0, 0)
+	CacheDuration = time.Hour
+	CacheStaleWindow = staleWindow
+	CacheTTLs = make(map[string]time.Duration)
+}
+
+func TestFlightGroupDo(t *testing.T) {
+	g := &flightGroup{calls: make(map[string]*flightCall)}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int64
+	fn := func() ([]byte, error) {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return []byte("value"), nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	var shared int64
+	results := make(chan string, callers)
+	call := func() {
+		defer wg.Done()
+		value, err, isShared := g.Do("key", fn)
+		if err != nil {
+			t.Error(err)
+		}
+		if isShared {
+			atomic.AddInt64(&shared, 1)
+		}
+		results <- string(value)
+	}
+
+	wg.Add(callers)
+	go call()
+	<-started
+	for i := 1; i < callers; i++ {
+		go call()
+	}
+	// let the other callers reach Do while the first call is still running
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	if n := atomic.LoadInt64(&shared); n != callers-1 {
+		t.Errorf("%d callers shared the result, want %d", n, callers-1)
+	}
+	for value := range results {
+		if value != "value" {
+			t.Errorf("caller got %q", value)
+		}
+	}
+
+	// a finished call is not shared with later callers
+	if _, _, isShared := g.Do("key", fn); isShared {
+		t.Error("Do shared the result of a finished call")
+	}
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Errorf("fn ran %d times after the first call finished, want 2", n)
+	}
+}
+
+func TestFlightGroupTryDo(t *testing.T) {
+	g := &flightGroup{calls: make(map[string]*flightCall)}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		g.Do("key", func() ([]byte, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+
+	if _, _, ran := g.TryDo("key", func() ([]byte, error) { return nil, nil }); ran {
+		t.Error("TryDo ran while a call of the key was running")
+	}
+	if _, _, ran := g.TryDo("other", func() ([]byte, error) { return nil, nil }); !ran {
+		t.Error("TryDo did not run a call of an idle key")
+	}
+
+	close(release)
+	<-done
+	if _, _, ran := g.TryDo("key", func() ([]byte, error) { return nil, nil }); !ran {
+		t.Error("TryDo did not run once the call of the key finished")
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	g := &flightGroup{calls: make(map[string]*flightCall)}
+
+	_, err, _ := g.Do("key", func() ([]byte, error) { panic("boom") })
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Do with a panicking fn error %v", err)
+	}
+	if value, err, _ := g.Do("key", func() ([]byte, error) { return []byte("ok"), nil }); err != nil || string(value) != "ok" {
+		t.Errorf("Do after a panic = %q, %v", value, err)
+	}
+}
+
+func TestCacheFetchSingleFill(t *testing.T) {
+	useMemoryCache(t, 0)
+
+	release := make(chan struct{})
+	var computes int64
+	compute := func() (interface{}, error) {
+		atomic.AddInt64(&computes, 1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			var value int
+			if err := cacheFetch("test:single", &value, compute); err != nil || value != 42 {
+				t.Errorf("cacheFetch = %d, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&computes); n != 1 {
+		t.Errorf("computed %d times, want 1", n)
+	}
+
+	// the value is cached
+	var value int
+	if err := cacheFetch("test:single", &value, compute); err != nil || value != 42 {
+		t.Errorf("cacheFetch = %d, %v", value, err)
+	}
+	if n := atomic.LoadInt64(&computes); n != 1 {
+		t.Errorf("computed %d times after the value was cached, want 1", n)
+	}
+}
+
+func TestCacheFetchStaleWhileRevalidate(t *testing.T) {
+	useMemoryCache(t, time.Hour)
+	CacheTTLs["test:stale"] = 20 * time.Millisecond
+
+	var computes int64
+	release := make(chan struct{})
+	compute := func() (interface{}, error) {
+		n := atomic.AddInt64(&computes, 1)
+		if n > 1 {
+			<-release
+		}
+		return n, nil
+	}
+
+	var value int64
+	if err := cacheFetch("test:stale", &value, compute); err != nil || value != 1 {
+		t.Fatalf("first cacheFetch = %d, %v", value, err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	// stale reads return the previous value right away and start a single background compute
+	for i := 0; i < 3; i++ {
+		if err := cacheFetch("test:stale", &value, compute); err != nil || value != 1 {
+			t.Fatalf("stale cacheFetch = %d, %v, want the previous value", value, err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt64(&computes); n != 2 {
+		t.Errorf("computed %d times while stale, want one background compute", n)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for value != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		if err := cacheFetch("test:stale", &value, compute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if value != 2 {
+		t.Errorf("cacheFetch after revalidation = %d, want 2", value)
+	}
+}
+
+func TestCacheFetchRefreshDuringFill(t *testing.T) {
+	useMemoryCache(t, 0)
+	RegisterCachedQuery("test:refreshed", []string{"test_view"}, nil)
+
+	var computes int64
+	compute := func() (interface{}, error) {
+		n := atomic.AddInt64(&computes, 1)
+		if n == 1 {
+			EvictViews([]string{"test_view"})
+		}
+		return n, nil
+	}
+
+	// the first value may predate the refresh, it is returned but not cached
+	for want := int64(1); want <= 2; want++ {
+		var value int64
+		if err := cacheFetch("test:refreshed", &value, compute); err != nil || value != want {
+			t.Fatalf("cacheFetch = %d, %v, want %d", value, err, want)
+		}
+	}
+
+	var value int64
+	if err := cacheFetch("test:refreshed", &value, compute); err != nil || value != 2 {
+		t.Errorf("cacheFetch = %d, %v, want the cached 2", value, err)
+	}
+	if n := atomic.LoadInt64(&computes); n != 2 {
+		t.Errorf("computed %d times, want 2", n)
+	}
+}
@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"container/list"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryCache is an in-process Cache bounded by the total size of its encoded values, least recently used
+// entries are evicted first
+type memoryCache struct {
+	sync.Mutex
+	cacheCounters
+
+	defaultTTL time.Duration
+	maxBytes   int64
+	bytes      int64
+
+	items     map[string]*list.Element
+	evictList *list.List
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns an in-process Cache holding at most maxBytes of encoded values, 0 means unbounded.
+// Entries set without a ttl expire after defaultTTL, 0 means never.
+func NewMemoryCache(maxBytes int64, defaultTTL time.Duration) Cache {
+	return &memoryCache{
+		defaultTTL: defaultTTL,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		evictList:  list.New(),
+	}
+}
+
+func (c *memoryCache) Get(key string, dest interface{}) (bool, error) {
+	c.Lock()
+	element, ok := c.items[key]
+	if ok && c.expired(element.Value.(*memoryCacheEntry)) {
+		c.removeElement(element)
+		ok = false
+	}
+	if !ok {
+		c.Unlock()
+		c.miss()
+		return false, nil
+	}
+	c.evictList.MoveToFront(element)
+	value := element.Value.(*memoryCacheEntry).value
+	c.Unlock()
+
+	c.hit()
+	if err := json.Unmarshal(value, dest); err != nil {
+		c.failed()
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *memoryCache) Set(key string, value interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		c.failed()
+		return err
+	}
+
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+	entry := &memoryCacheEntry{key: key, value: b}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+
+	// a value larger than the whole budget is not cached
+	if c.maxBytes > 0 && int64(len(b)) > c.maxBytes {
+		return nil
+	}
+
+	c.items[key] = c.evictList.PushFront(entry)
+	c.bytes += int64(len(b))
+	c.set()
+
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.removeElement(c.evictList.Back())
+		c.evicted(1)
+	}
+	return nil
+}
+
+func (c *memoryCache) Delete(keys ...string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.removeElement(element)
+		}
+	}
+	return nil
+}
+
+func (c *memoryCache) Flush() error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.evictList.Init()
+	c.bytes = 0
+	return nil
+}
+
+func (c *memoryCache) Keys() ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	keys := make([]string, 0, len(c.items))
+	for key, element := range c.items {
+		if !c.expired(element.Value.(*memoryCacheEntry)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (c *memoryCache) Stats() CacheStats {
+	stats := c.cacheCounters.stats("memory")
+
+	c.Lock()
+	defer c.Unlock()
+
+	stats.Keys = int64(len(c.items))
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	return stats
+}
+
+func (c *memoryCache) expired(entry *memoryCacheEntry) bool {
+	return !entry.expires.IsZero() && time.Now().After(entry.expires)
+}
+
+func (c *memoryCache) removeElement(element *list.Element) {
+	entry := element.Value.(*memoryCacheEntry)
+	c.evictList.Remove(element)
+	delete(c.items, entry.key)
+	c.bytes -= int64(len(entry.value))
+}
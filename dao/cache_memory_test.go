@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheGetSet(t *testing.T) {
+	cache := NewMemoryCache(0, 0)
+
+	var value []string
+	if ok, err := cache.Get("missing", &value); err != nil || ok {
+		t.Fatalf("Get(missing) = %v, %v, want false, nil", ok, err)
+	}
+
+	if err := cache.Set("sps", []string{"f01", "f02"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cache.Get("sps", &value); err != nil || !ok {
+		t.Fatalf("Get(sps) = %v, %v, want true, nil", ok, err)
+	}
+	if strings.Join(value, ",") != "f01,f02" {
+		t.Errorf("Get(sps) decoded %v", value)
+	}
+
+	if err := cache.Delete("sps", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := cache.Get("sps", &value); ok {
+		t.Errorf("Get(sps) after Delete found the key")
+	}
+}
+
+func TestMemoryCacheByteBudget(t *testing.T) {
+	// "xxxxxxxx" encodes to 10 bytes of json with its quotes
+	cache := NewMemoryCache(30, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(key, "xxxxxxxx", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reading a makes b the least recently used entry
+	var value string
+	if ok, _ := cache.Get("a", &value); !ok {
+		t.Fatal("Get(a) missed before the budget was reached")
+	}
+	if err := cache.Set("d", "xxxxxxxx", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := cache.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "a,c,d" {
+		t.Errorf("Keys() = %v, want [a c d]", keys)
+	}
+
+	stats := cache.Stats()
+	if stats.Bytes != 30 || stats.Keys != 3 || stats.Evictions != 1 || stats.MaxBytes != 30 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// a value larger than the whole budget is dropped without evicting anything
+	if err := cache.Set("huge", strings.Repeat("x", 64), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := cache.Get("huge", &value); ok {
+		t.Errorf("Get(huge) found a value larger than the budget")
+	}
+	if stats := cache.Stats(); stats.Keys != 3 || stats.Bytes != 30 {
+		t.Errorf("Stats() after an oversized Set = %+v", stats)
+	}
+
+	// replacing a key releases the size of its previous value
+	if err := cache.Set("a", "x", 0); err != nil {
+		t.Fatal(err)
+	}
+	if stats := cache.Stats(); stats.Bytes != 23 || stats.Evictions != 1 {
+		t.Errorf("Stats() after replacing a = %+v", stats)
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	cache := NewMemoryCache(0, time.Hour)
+
+	if err := cache.Set("short", 1, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set("default", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set("long", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	if ok, _ := cache.Get("short", &value); !ok {
+		t.Fatal("Get(short) missed before its ttl")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if ok, _ := cache.Get("short", &value); ok {
+		t.Errorf("Get(short) found the key after its ttl")
+	}
+
+	keys, err := cache.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "default,long" {
+		t.Errorf("Keys() = %v, want [default long]", keys)
+	}
+	if stats := cache.Stats(); stats.Keys != 2 || stats.Bytes != 2 {
+		t.Errorf("Stats() = %+v, the expired entry was not released", stats)
+	}
+}
+
+func TestMemoryCacheFlush(t *testing.T) {
+	cache := NewMemoryCache(100, 0)
+	for _, key := range []string{"a", "b"} {
+		if err := cache.Set(key, key, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := cache.Keys(); len(keys) != 0 {
+		t.Errorf("Keys() after Flush = %v", keys)
+	}
+	if stats := cache.Stats(); stats.Bytes != 0 || stats.Keys != 0 {
+		t.Errorf("Stats() after Flush = %+v", stats)
+	}
+}
@@ -0,0 +1,319 @@
+package dao
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	redisPoolSize    = 10
+	redisScanCount   = 100
+	redisDeleteBatch = 100
+)
+
+// redisCache is a Cache stored in a Redis compatible server, shared by every replica using the same server and prefix.
+// It speaks RESP over plain TCP, keys are namespaced with prefix.
+type redisCache struct {
+	cacheCounters
+
+	addr       string
+	password   string
+	db         int
+	prefix     string
+	defaultTTL time.Duration
+	timeout    time.Duration
+
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisCache returns a Cache stored in the Redis compatible server at addr. Keys are prefixed with prefix so
+// several services can share a database, entries set without a ttl expire after defaultTTL, 0 means never.
+func NewRedisCache(addr, password string, db int, prefix string, defaultTTL, timeout time.Duration) Cache {
+	return &redisCache{
+		addr:       addr,
+		password:   password,
+		db:         db,
+		prefix:     prefix,
+		defaultTTL: defaultTTL,
+		timeout:    timeout,
+		pool:       make(chan *redisConn, redisPoolSize),
+	}
+}
+
+func (c *redisCache) Get(key string, dest interface{}) (bool, error) {
+	reply, err := c.do("GET", c.prefix+key)
+	if err != nil {
+		c.failed()
+		return false, err
+	}
+	if reply == nil {
+		c.miss()
+		return false, nil
+	}
+
+	b, ok := reply.([]byte)
+	if !ok {
+		c.failed()
+		return false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	c.hit()
+	if err := json.Unmarshal(b, dest); err != nil {
+		c.failed()
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *redisCache) Set(key string, value interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		c.failed()
+		return err
+	}
+
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+	args := []string{"SET", c.prefix + key, string(b)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	if _, err := c.do(args...); err != nil {
+		c.failed()
+		return err
+	}
+	c.set()
+	return nil
+}
+
+func (c *redisCache) Delete(keys ...string) error {
+	for start := 0; start < len(keys); start += redisDeleteBatch {
+		end := start + redisDeleteBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		args := []string{"DEL"}
+		for _, key := range keys[start:end] {
+			args = append(args, c.prefix+key)
+		}
+		if _, err := c.do(args...); err != nil {
+			c.failed()
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush deletes the keys under the prefix only, other data in the database is left alone
+func (c *redisCache) Flush() error {
+	keys, err := c.Keys()
+	if err != nil {
+		return err
+	}
+	return c.Delete(keys...)
+}
+
+func (c *redisCache) Keys() ([]string, error) {
+	pattern := redisGlobEscape(c.prefix) + "*"
+
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			c.failed()
+			return nil, err
+		}
+
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("redis: unexpected SCAN reply %T", reply)
+		}
+		next, _ := parts[0].([]byte)
+		batch, _ := parts[1].([]interface{})
+		for _, key := range batch {
+			if b, ok := key.([]byte); ok {
+				keys = append(keys, strings.TrimPrefix(string(b), c.prefix))
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Stats leaves Keys and Bytes at -1, counting them would mean scanning the server
+func (c *redisCache) Stats() CacheStats {
+	stats := c.cacheCounters.stats("redis")
+	stats.Keys = -1
+	stats.Bytes = -1
+	return stats
+}
+
+// do sends a command and reads its reply. Connections are reused unless they failed at the network level.
+func (c *redisCache) do(args ...string) (interface{}, error) {
+	conn, err := c.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(c.timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	c.putConn(conn)
+	return reply, err
+}
+
+func (c *redisCache) getConn() (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	netConn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, r: bufio.NewReader(netConn)}
+
+	if c.password != "" {
+		if _, err := conn.do(c.timeout, "AUTH", c.password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := conn.do(c.timeout, "SELECT", strconv.Itoa(c.db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisCache) putConn(conn *redisConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (r *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout > 0 {
+		if err := r.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return r.readReply()
+}
+
+// readReply reads a RESP reply, bulk strings are returned as []byte, arrays as []interface{} and nil replies as nil
+func (r *redisConn) readReply() (interface{}, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = r.readReply(); err != nil {
+				var replyErr redisError
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
+
+// redisGlobEscape escapes the glob characters of a SCAN MATCH pattern
+func redisGlobEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
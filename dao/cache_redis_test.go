@@ -0,0 +1,403 @@
+package dao
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process RESP server implementing the commands used by redisCache
+type fakeRedis struct {
+	sync.Mutex
+	listener net.Listener
+	conns    int64
+
+	values  map[string]string
+	expires map[string]time.Time
+
+	// failKey makes commands on that key answer with an error reply, dropKey makes them close the connection
+	failKey string
+	dropKey string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{listener: listener, values: make(map[string]string), expires: make(map[string]time.Time)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&s.conns, 1)
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) connections() int64 {
+	return atomic.LoadInt64(&s.conns)
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		reply, ok := s.exec(args)
+		if !ok {
+			return
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs a command and returns its encoded reply, ok is false when the connection must be dropped
+func (s *fakeRedis) exec(args []string) (reply string, ok bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(args) > 1 {
+		if args[1] == s.dropKey {
+			return "", false
+		}
+		if args[1] == s.failKey {
+			return "-ERR injected failure\r\n", true
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, found := s.get(args[1])
+		if !found {
+			return "$-1\r\n", true
+		}
+		return bulkString(value), true
+	case "SET":
+		s.values[args[1]] = args[2]
+		delete(s.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, err := strconv.ParseInt(args[4], 10, 64)
+			if err != nil {
+				return "-ERR value is not an integer\r\n", true
+			}
+			s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n", true
+	case "DEL":
+		var n int
+		for _, key := range args[1:] {
+			if _, found := s.get(key); found {
+				delete(s.values, key)
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n), true
+	case "SCAN":
+		return s.scan(args), true
+	default:
+		return "-ERR unknown command\r\n", true
+	}
+}
+
+// scan pages through the sorted keys one at a time so the client has to follow the cursor
+func (s *fakeRedis) scan(args []string) string {
+	cursor, _ := strconv.Atoi(args[1])
+	pattern := "*"
+	for i := 2; i+1 < len(args); i += 2 {
+		if strings.ToUpper(args[i]) == "MATCH" {
+			pattern = args[i+1]
+		}
+	}
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	next := 0
+	var batch []string
+	if cursor < len(keys) {
+		if ok, _ := path.Match(pattern, keys[cursor]); ok {
+			if _, found := s.get(keys[cursor]); found {
+				batch = append(batch, keys[cursor])
+			}
+		}
+		if cursor+1 < len(keys) {
+			next = cursor + 1
+		}
+	}
+
+	reply := "*2\r\n" + bulkString(strconv.Itoa(next)) + fmt.Sprintf("*%d\r\n", len(batch))
+	for _, key := range batch {
+		reply += bulkString(key)
+	}
+	return reply
+}
+
+func (s *fakeRedis) get(key string) (string, bool) {
+	value, found := s.values[key]
+	if !found {
+		return "", false
+	}
+	if expires, ok := s.expires[key]; ok && time.Now().After(expires) {
+		delete(s.values, key)
+		delete(s.expires, key)
+		return "", false
+	}
+	return value, true
+}
+
+func (s *fakeRedis) ttl(key string) time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	expires, ok := s.expires[key]
+	if !ok {
+		return 0
+	}
+	return time.Until(expires)
+}
+
+func (s *fakeRedis) put(key, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.values[key] = value
+}
+
+func (s *fakeRedis) has(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, found := s.get(key)
+	return found
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	server := newFakeRedis(t)
+	cache := NewRedisCache(server.addr(), "", 0, "metrics:", time.Hour, time.Second)
+
+	var value map[string]int
+	ok, err := cache.Get("missing", &value)
+	if err != nil || ok {
+		t.Fatalf("Get(missing) = %v, %v, want false, nil", ok, err)
+	}
+
+	if err := cache.Set("totals", map[string]int{"deals": 3}, 0); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = cache.Get("totals", &value)
+	if err != nil || !ok {
+		t.Fatalf("Get(totals) = %v, %v, want true, nil", ok, err)
+	}
+	if value["deals"] != 3 {
+		t.Errorf("Get(totals) decoded %v", value)
+	}
+	if !server.has("metrics:totals") {
+		t.Errorf("key is not stored under the prefix")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Sets != 1 || stats.Errors != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	server := newFakeRedis(t)
+	cache := NewRedisCache(server.addr(), "", 0, "metrics:", time.Hour, time.Second)
+
+	if err := cache.Set("default", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.ttl("metrics:default"); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("default ttl = %v, want about 1h", ttl)
+	}
+
+	if err := cache.Set("short", 1, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.ttl("metrics:short"); ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Errorf("ttl = %v, want at most 50ms", ttl)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	var value int
+	if ok, err := cache.Get("short", &value); err != nil || ok {
+		t.Errorf("Get(short) after its ttl = %v, %v, want false, nil", ok, err)
+	}
+
+	unbounded := NewRedisCache(server.addr(), "", 0, "metrics:", 0, time.Second)
+	if err := unbounded.Set("forever", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.ttl("metrics:forever"); ttl != 0 {
+		t.Errorf("ttl without a default = %v, want none", ttl)
+	}
+}
+
+func TestRedisCacheDelete(t *testing.T) {
+	server := newFakeRedis(t)
+	cache := NewRedisCache(server.addr(), "", 0, "metrics:", 0, time.Second)
+
+	keys := make([]string, redisDeleteBatch+5)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%03d", i)
+		if err := cache.Set(keys[i], i, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Set("kept", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Delete(append(keys, "missing")...); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if server.has("metrics:" + key) {
+			t.Fatalf("%s was not deleted", key)
+		}
+	}
+	if !server.has("metrics:kept") {
+		t.Errorf("kept was deleted")
+	}
+}
+
+func TestRedisCacheFlushPrefix(t *testing.T) {
+	server := newFakeRedis(t)
+	server.put("other:key", "1")
+	server.put("metrics", "1")
+
+	cache := NewRedisCache(server.addr(), "", 0, "metrics:", 0, time.Second)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(key, key, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := cache.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Errorf("Keys() = %v, want [a b c]", keys)
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := cache.Keys(); err != nil || len(keys) != 0 {
+		t.Errorf("Keys() after Flush = %v, %v, want none", keys, err)
+	}
+	if !server.has("other:key") || !server.has("metrics") {
+		t.Errorf("Flush deleted keys outside its prefix")
+	}
+}
+
+func TestRedisCachePoolReuse(t *testing.T) {
+	server := newFakeRedis(t)
+	server.Lock()
+	server.failKey = "metrics:bad"
+	server.dropKey = "metrics:drop"
+	server.Unlock()
+	cache := NewRedisCache(server.addr(), "", 0, "metrics:", 0, time.Second)
+
+	if err := cache.Set("a", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.connections(); n != 1 {
+		t.Fatalf("connections = %d, want 1", n)
+	}
+
+	// an error reply leaves the connection usable, it goes back to the pool
+	var value int
+	if _, err := cache.Get("bad", &value); err == nil {
+		t.Fatal("Get(bad) did not fail")
+	}
+	if ok, err := cache.Get("a", &value); err != nil || !ok {
+		t.Fatalf("Get(a) after an error reply = %v, %v", ok, err)
+	}
+	if n := server.connections(); n != 1 {
+		t.Errorf("connections after an error reply = %d, want 1", n)
+	}
+
+	// a network error discards the connection, the next command dials a new one
+	if _, err := cache.Get("drop", &value); err == nil {
+		t.Fatal("Get(drop) did not fail")
+	}
+	if ok, err := cache.Get("a", &value); err != nil || !ok {
+		t.Fatalf("Get(a) after a dropped connection = %v, %v", ok, err)
+	}
+	if n := server.connections(); n != 2 {
+		t.Errorf("connections after a dropped connection = %d, want 2", n)
+	}
+
+	if stats := cache.Stats(); stats.Errors != 2 {
+		t.Errorf("Stats().Errors = %d, want 2", stats.Errors)
+	}
+}
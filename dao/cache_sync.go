@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// refreshSyncOverlap how far back each poll of WatchRefreshes looks before the previous one, so refreshes recorded
+// late or by an instance with a skewed clock are still seen
+const refreshSyncOverlap = time.Minute
+
+// refreshSync tracks the refresh jobs whose views were already evicted by this instance
+var refreshSync = struct {
+	sync.Mutex
+	since time.Time
+	seen  map[string]time.Time
+}{seen: make(map[string]time.Time)}
+
+// WatchRefreshes polls view_refresh_jobs every interval and evicts the cache keys of views refreshed by other
+// instances. Refreshes run by this instance are evicted as soon as they finish, a cache shared between the
+// instances does not need it.
+func WatchRefreshes(interval time.Duration) {
+	refreshSync.Lock()
+	refreshSync.since = time.Now().UTC()
+	refreshSync.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := syncRefreshes(); err != nil {
+				log.Printf("unable to read the view refreshes of other instances, the error is '%v'", err)
+			}
+		}
+	}()
+}
+
+// syncRefreshes evicts the views of the refreshes finished since the previous poll that were not evicted yet
+func syncRefreshes() error {
+	refreshSync.Lock()
+	since := refreshSync.since
+	refreshSync.Unlock()
+
+	polledAt := time.Now().UTC()
+	var jobs []*RefreshJob
+	err := DB.Where("status IN (?) AND finished_at > ?", []string{RefreshStatusSucceeded, RefreshStatusPartial}, since.Add(-refreshSyncOverlap)).
+		Order("finished_at").
+		Find(&jobs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if !markRefreshSynced(job.JobID, job.FinishedAt) {
+			continue
+		}
+
+		views, err := ViewsFor(job.ViewName)
+		if err != nil {
+			continue
+		}
+		decodeRefreshErrors(job)
+
+		event := &RefreshEvent{Job: *job}
+		for _, view := range views {
+			event.Views = append(event.Views, view.Name)
+		}
+		event.Refreshed = refreshedViews(job, event.Views)
+		evictRefreshedViews(event)
+	}
+
+	refreshSync.Lock()
+	defer refreshSync.Unlock()
+
+	refreshSync.since = polledAt
+	for jobID, finishedAt := range refreshSync.seen {
+		if finishedAt.Before(polledAt.Add(-2 * refreshSyncOverlap)) {
+			delete(refreshSync.seen, jobID)
+		}
+	}
+	return nil
+}
+
+// markRefreshSynced records that the views of jobID were evicted, it returns false when they already were.
+// Nothing is recorded until WatchRefreshes is started.
+func markRefreshSynced(jobID string, finishedAt *time.Time) bool {
+	refreshSync.Lock()
+	defer refreshSync.Unlock()
+
+	if refreshSync.since.IsZero() {
+		return true
+	}
+	if _, ok := refreshSync.seen[jobID]; ok {
+		return false
+	}
+
+	refreshSync.seen[jobID] = time.Now().UTC()
+	if finishedAt != nil {
+		refreshSync.seen[jobID] = *finishedAt
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"log"
+	"sync"
+)
+
+// CachePrewarm when set, cache entries evicted after a view refresh are recomputed right away
+var CachePrewarm bool
+
+// cachedQuery is a Cacher key with the views its value is read from
+type cachedQuery struct {
+	views []string
+	warm  func() error
+}
+
+var cachedQueries = struct {
+	sync.RWMutex
+	byKey map[string]*cachedQuery
+
+	// generations counts the refreshes of each view, a fill started before a refresh of its views is not cached
+	generations map[string]uint64
+}{byKey: make(map[string]*cachedQuery), generations: make(map[string]uint64)}
+
+func init() {
+	OnRefreshComplete(evictRefreshedViews)
+}
+
+// RegisterCachedQuery tags the Cacher key with the views its value is read from, so it is evicted when one of them
+// is refreshed. warm recomputes and caches the value, it is called after eviction when CachePrewarm is set.
+// Keys read from the log tables rather than views are not registered, they expire with the Cacher ttl.
+func RegisterCachedQuery(key string, views []string, warm func() error) {
+	cachedQueries.Lock()
+	defer cachedQueries.Unlock()
+
+	cachedQueries.byKey[key] = &cachedQuery{views: views, warm: warm}
+}
+
+// EvictViews removes the Cacher keys tagged with any of views and returns them
+func EvictViews(views []string) []string {
+	refreshed := make(map[string]bool, len(views))
+	for _, view := range views {
+		refreshed[view] = true
+	}
+
+	cachedQueries.Lock()
+	for _, view := range views {
+		cachedQueries.generations[view]++
+	}
+
+	var evicted []string
+	for key, query := range cachedQueries.byKey {
+		for _, view := range query.views {
+			if refreshed[view] {
+				evicted = append(evicted, key)
+				break
+			}
+		}
+	}
+	cachedQueries.Unlock()
+
+	if Cacher != nil && len(evicted) > 0 {
+		if err := Cacher.Delete(evicted...); err != nil {
+			log.Printf("unable to evict cache keys %v, the error is '%v'", evicted, err)
+		}
+	}
+	return evicted
+}
+
+// taggedViews returns the views key is tagged with, none when it is not registered
+func taggedViews(key string) []string {
+	cachedQueries.RLock()
+	defer cachedQueries.RUnlock()
+
+	if query := cachedQueries.byKey[key]; query != nil {
+		return query.views
+	}
+	return nil
+}
+
+// viewsGeneration is the sum of the generations of views, it changes whenever one of them is refreshed
+func viewsGeneration(views []string) uint64 {
+	cachedQueries.RLock()
+	defer cachedQueries.RUnlock()
+
+	var generation uint64
+	for _, view := range views {
+		generation += cachedQueries.generations[view]
+	}
+	return generation
+}
+
+// evictRefreshedViews is the refresh listener evicting, and with CachePrewarm recomputing, the keys of refreshed views.
+// Views whose statement failed are unchanged, so their keys are kept.
+func evictRefreshedViews(event *RefreshEvent) {
+	markRefreshSynced(event.Job.JobID, event.Job.FinishedAt)
+	if len(event.Refreshed) == 0 {
+		return
+	}
+
+	evicted := EvictViews(event.Refreshed)
+	if !CachePrewarm {
+		return
+	}
+
+	for _, key := range evicted {
+		cachedQueries.RLock()
+		query := cachedQueries.byKey[key]
+		cachedQueries.RUnlock()
+
+		if query == nil || query.warm == nil {
+			continue
+		}
+		if err := query.warm(); err != nil {
+			log.Printf("unable to prewarm cache key %s, the error is '%v'", key, err)
+		}
+	}
+}
@@ -17,39 +17,23 @@ var (
 )
 
 // GetAllContentDealLogs is a function to get a slice of record(s) from content_deal_logs table in the estuary database
-// params - page     - page requested (defaults to 0)
-// params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
+// params - query    - page or cursor, pagesize, sort order, column filters, count mode and fields of the request
 // error - ErrNotFound, db Find error
-func GetAllContentDealLogs(ctx context.Context, page, pagesize int64, order string) (results []*model.ContentDealLogs, totalRows int, err error) {
+func GetAllContentDealLogs(ctx context.Context, query *ListQuery) (results []*model.ContentDealLogs, totalRows int, nextCursor string, err error) {
 
-	resultOrm := DB.Model(&model.ContentDealLogs{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
+	totalRows, nextCursor, err = listRows(DB.Model(&model.ContentDealLogs{}), "content_deal_logs", query, &results)
+	if err != nil {
+		return nil, -1, "", err
 	}
-
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
-		return nil, -1, err
-	}
-
-	return results, totalRows, nil
+	return results, totalRows, nextCursor, nil
 }
 
 // GetContentDealLogs is a function to get a single record from the content_deal_logs table in the estuary database
+// params - fields   - columns to select, empty selects every column
 // error - ErrNotFound, db Find error
-func GetContentDealLogs(ctx context.Context, argID int64) (record *model.ContentDealLogs, err error) {
+func GetContentDealLogs(ctx context.Context, argID int64, fields []string) (record *model.ContentDealLogs, err error) {
 	record = &model.ContentDealLogs{}
-	if err = DB.First(record, argID).Error; err != nil {
+	if err = applyFields(DB, fields).First(record, argID).Error; err != nil {
 		err = ErrNotFound
 		return record, err
 	}
@@ -17,39 +17,23 @@ var (
 )
 
 // GetAllContentWalletLogs is a function to get a slice of record(s) from content_wallet_logs table in the estuary database
-// params - page     - page requested (defaults to 0)
-// params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
+// params - query    - page or cursor, pagesize, sort order, column filters, count mode and fields of the request
 // error - ErrNotFound, db Find error
-func GetAllContentWalletLogs(ctx context.Context, page, pagesize int64, order string) (results []*model.ContentWalletLogs, totalRows int, err error) {
+func GetAllContentWalletLogs(ctx context.Context, query *ListQuery) (results []*model.ContentWalletLogs, totalRows int, nextCursor string, err error) {
 
-	resultOrm := DB.Model(&model.ContentWalletLogs{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
+	totalRows, nextCursor, err = listRows(DB.Model(&model.ContentWalletLogs{}), "content_wallet_logs", query, &results)
+	if err != nil {
+		return nil, -1, "", err
 	}
-
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
-		return nil, -1, err
-	}
-
-	return results, totalRows, nil
+	return results, totalRows, nextCursor, nil
 }
 
 // GetContentWalletLogs is a function to get a single record from the content_wallet_logs table in the estuary database
+// params - fields   - columns to select, empty selects every column
 // error - ErrNotFound, db Find error
-func GetContentWalletLogs(ctx context.Context, argID int64) (record *model.ContentWalletLogs, err error) {
+func GetContentWalletLogs(ctx context.Context, argID int64, fields []string) (record *model.ContentWalletLogs, err error) {
 	record = &model.ContentWalletLogs{}
-	if err = DB.First(record, argID).Error; err != nil {
+	if err = applyFields(DB, fields).First(record, argID).Error; err != nil {
 		err = ErrNotFound
 		return record, err
 	}
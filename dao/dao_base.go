@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jinzhu/gorm"
-	explru "github.com/paskal/golang-lru/simplelru"
 	"reflect"
 )
 
@@ -55,7 +54,8 @@ var (
 	// DB reference to database
 	DB *gorm.DB
 
-	Cacher *explru.ExpirableLRU
+	// Cacher cache of the stats queries
+	Cacher Cache
 
 	// AppBuildInfo reference to build info
 	AppBuildInfo *BuildInfo
@@ -0,0 +1,403 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// ViewStateOK the view exists and matches the sql it was created from
+	ViewStateOK = "ok"
+
+	// ViewStateNotApplied the view has never been created by a migration
+	ViewStateNotApplied = "not_applied"
+
+	// ViewStateMissing the view was created but is no longer in pg_matviews
+	ViewStateMissing = "missing"
+
+	// ViewStateChangedOnDisk the create sql file changed since the view was created, migrate up recreates it
+	ViewStateChangedOnDisk = "changed_on_disk"
+
+	// ViewStateChangedInDB the definition in pg_matviews no longer matches the one recorded when the view was created
+	ViewStateChangedInDB = "changed_in_db"
+
+	// migrateLockID key of the advisory lock serialising migrations of several instances
+	migrateLockID = 8315047
+)
+
+// ErrIrreversibleMigration error when migrating down past a migration without a Down step
+var ErrIrreversibleMigration = fmt.Errorf("migration cannot be reverted")
+
+// Migration is a versioned schema change, Down is nil for migrations that cannot be reverted
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is an applied migration, recorded in the schema_migrations table
+type SchemaMigration struct {
+	Version   int64     `gorm:"primary_key;column:version;type:INT8;" json:"version"`
+	Name      string    `gorm:"column:name;type:TEXT;" json:"name"`
+	AppliedAt time.Time `gorm:"column:applied_at;type:TIMESTAMPTZ;" json:"appliedAt"`
+}
+
+// TableName sets the insert table name for this struct type
+func (m *SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// SchemaView records the sql a materialized view was created from, in the schema_views table
+type SchemaView struct {
+	Name string `gorm:"primary_key;column:name;type:TEXT;" json:"name"`
+
+	// Checksum sha256 of the create sql file
+	Checksum string `gorm:"column:checksum;type:TEXT;" json:"checksum"`
+
+	// DefinitionChecksum sha256 of the pg_matviews definition right after the view was created
+	DefinitionChecksum string    `gorm:"column:definition_checksum;type:TEXT;" json:"definitionChecksum"`
+	AppliedAt          time.Time `gorm:"column:applied_at;type:TIMESTAMPTZ;" json:"appliedAt"`
+}
+
+// TableName sets the insert table name for this struct type
+func (v *SchemaView) TableName() string {
+	return "schema_views"
+}
+
+// MigrationStatus is the state of the versioned migrations and of the materialized views
+type MigrationStatus struct {
+	Applied []*SchemaMigration `json:"applied"`
+	Pending []*MigrationInfo   `json:"pending"`
+	Views   []*ViewStatus      `json:"views"`
+}
+
+// MigrationInfo names a migration that has not been applied
+type MigrationInfo struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+}
+
+// ViewStatus is the drift state of a registered view
+type ViewStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// MigrateUp applies the pending migrations, then recreates the views whose create sql changed on disk
+func MigrateUp() ([]*MigrationInfo, error) {
+	var applied []*MigrationInfo
+	err := migrateTx(func(tx *gorm.DB) error {
+		done, err := appliedVersions(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range Migrations {
+			if done[migration.Version] {
+				continue
+			}
+
+			if err := migration.Up(tx); err != nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+			}
+			record := &SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+			applied = append(applied, &MigrationInfo{Version: migration.Version, Name: migration.Name})
+		}
+
+		if _, err := syncViews(tx); err != nil {
+			return fmt.Errorf("view sync: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the latest applied migration
+// error - ErrIrreversibleMigration when it has no Down step
+func MigrateDown() (*MigrationInfo, error) {
+	var reverted *MigrationInfo
+	err := migrateTx(func(tx *gorm.DB) error {
+		var latest SchemaMigration
+		res := tx.Order("version desc").First(&latest)
+		if res.RecordNotFound() {
+			return nil
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+
+		migration := migrationByVersion(latest.Version)
+		if migration == nil {
+			return fmt.Errorf("applied migration %d %s is not known to this build", latest.Version, latest.Name)
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("%w: %d %s", ErrIrreversibleMigration, migration.Version, migration.Name)
+		}
+
+		if err := migration.Down(tx); err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		if err := tx.Delete(&latest).Error; err != nil {
+			return err
+		}
+		reverted = &MigrationInfo{Version: migration.Version, Name: migration.Name}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetMigrationStatus returns the applied and pending migrations and the drift state of every registered view.
+// It only reads, a database where migrate up never ran has nothing applied.
+func GetMigrationStatus() (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+	exists, err := tableExists(DB, (&SchemaMigration{}).TableName())
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if err := DB.Order("version").Find(&status.Applied).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	done := make(map[int64]bool, len(status.Applied))
+	for _, applied := range status.Applied {
+		done[applied.Version] = true
+	}
+	for _, migration := range Migrations {
+		if !done[migration.Version] {
+			status.Pending = append(status.Pending, &MigrationInfo{Version: migration.Version, Name: migration.Name})
+		}
+	}
+
+	views, err := viewStates(DB)
+	if err != nil {
+		return nil, err
+	}
+	status.Views = views
+	return status, nil
+}
+
+// migrateTx runs fn in a transaction holding the migration advisory lock
+func migrateTx(fn func(tx *gorm.DB) error) error {
+	if err := DB.AutoMigrate(&SchemaMigration{}, &SchemaView{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrateLockID).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+func appliedVersions(tx *gorm.DB) (map[int64]bool, error) {
+	var applied []*SchemaMigration
+	if err := tx.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]bool, len(applied))
+	for _, migration := range applied {
+		done[migration.Version] = true
+	}
+	return done, nil
+}
+
+func migrationByVersion(version int64) *Migration {
+	for _, migration := range Migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// viewStates compares every registered view with its create sql and its pg_matviews definition
+func viewStates(tx *gorm.DB) ([]*ViewStatus, error) {
+	recorded, err := recordedViews(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []*ViewStatus
+	for _, view := range orderedViews() {
+		state, err := viewState(tx, view, recorded[view.Name])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, &ViewStatus{Name: view.Name, State: state})
+	}
+	return states, nil
+}
+
+func viewState(tx *gorm.DB, view *MaterializedView, recorded *SchemaView) (string, error) {
+	if recorded == nil {
+		return ViewStateNotApplied, nil
+	}
+
+	createSQL, err := view.CreateSQL()
+	if err != nil {
+		return "", err
+	}
+	if checksum(createSQL) != recorded.Checksum {
+		return ViewStateChangedOnDisk, nil
+	}
+
+	definition, found, err := matviewDefinition(tx, view.Name)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return ViewStateMissing, nil
+	}
+	if checksum(definition) != recorded.DefinitionChecksum {
+		return ViewStateChangedInDB, nil
+	}
+	return ViewStateOK, nil
+}
+
+// syncViews creates the views that were never created or are missing, and recreates the ones whose create sql
+// changed on disk along with the views depending on them. Views changed in the database are reported, not replaced.
+func syncViews(tx *gorm.DB) ([]*ViewStatus, error) {
+	states, err := viewStates(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := make(map[string]bool)
+	for _, state := range states {
+		switch state.State {
+		case ViewStateNotApplied, ViewStateMissing, ViewStateChangedOnDisk:
+			stale[state.Name] = true
+		case ViewStateChangedInDB:
+			fmt.Printf("view %s was changed in the database, it no longer matches its sql\n", state.Name)
+		}
+	}
+
+	ordered := orderedViews()
+	// a recreated view takes the views built on it along
+	for _, view := range ordered {
+		for _, dep := range view.DependsOn {
+			if stale[dep] {
+				stale[view.Name] = true
+			}
+		}
+	}
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if stale[ordered[i].Name] {
+			if err := tx.Exec("DROP MATERIALIZED VIEW IF EXISTS " + ordered[i].Name).Error; err != nil {
+				return nil, fmt.Errorf("drop view %s: %w", ordered[i].Name, err)
+			}
+		}
+	}
+
+	for _, view := range ordered {
+		if !stale[view.Name] {
+			continue
+		}
+		if err := createView(tx, view); err != nil {
+			return nil, err
+		}
+	}
+
+	return viewStates(tx)
+}
+
+// createView runs the create sql of view and records its checksums
+func createView(tx *gorm.DB, view *MaterializedView) error {
+	createSQL, err := view.CreateSQL()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range splitSQLStatements(createSQL) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("create view %s: %w", view.Name, err)
+		}
+	}
+
+	definition, found, err := matviewDefinition(tx, view.Name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("create view %s: its sql did not create it", view.Name)
+	}
+
+	return tx.Save(&SchemaView{
+		Name:               view.Name,
+		Checksum:           checksum(createSQL),
+		DefinitionChecksum: checksum(definition),
+		AppliedAt:          time.Now().UTC(),
+	}).Error
+}
+
+// dropViews drops every registered view, dependents first
+func dropViews(tx *gorm.DB) error {
+	ordered := orderedViews()
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if err := tx.Exec("DROP MATERIALIZED VIEW IF EXISTS " + ordered[i].Name).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&SchemaView{}).Error
+}
+
+// recordedViews returns the schema_views rows by view name, none when the table was not created yet
+func recordedViews(tx *gorm.DB) (map[string]*SchemaView, error) {
+	exists, err := tableExists(tx, (&SchemaView{}).TableName())
+	if err != nil || !exists {
+		return map[string]*SchemaView{}, err
+	}
+
+	var views []*SchemaView
+	if err := tx.Find(&views).Error; err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]*SchemaView, len(views))
+	for _, view := range views {
+		recorded[view.Name] = view
+	}
+	return recorded, nil
+}
+
+func matviewDefinition(tx *gorm.DB, name string) (definition string, found bool, err error) {
+	rows, err := tx.Raw("SELECT definition FROM pg_matviews WHERE schemaname = current_schema() AND matviewname = ?", name).Rows()
+	if err != nil {
+		return "", false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", false, rows.Err()
+	}
+	err = rows.Scan(&definition)
+	return definition, err == nil, err
+}
+
+// tableExists reports whether table is in the search path
+func tableExists(tx *gorm.DB, table string) (bool, error) {
+	var exists bool
+	err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", table).Row().Scan(&exists)
+	return exists, err
+}
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
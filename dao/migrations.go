@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// logIndex is an index on a single column of a log table
+type logIndex struct {
+	Name   string
+	Table  string
+	Column string
+}
+
+// logIndexes are the indexes created by the log_indexes migration
+var logIndexes = []logIndex{
+	{"idx_content_deal_logs_created_at", "content_deal_logs", "created_at"},
+	{"idx_content_deal_logs_content", "content_deal_logs", "content"},
+	{"idx_content_deal_proposal_logs_created_at", "content_deal_proposal_logs", "created_at"},
+	{"idx_content_deal_proposal_parameters_logs_created_at", "content_deal_proposal_parameters_logs", "created_at"},
+	{"idx_content_logs_created_at", "content_logs", "created_at"},
+	{"idx_content_logs_system_content_id", "content_logs", "system_content_id"},
+	{"idx_content_logs_status", "content_logs", "status"},
+	{"idx_content_logs_piece_commitment_id", "content_logs", "piece_commitment_id"},
+	{"idx_content_miner_logs_created_at", "content_miner_logs", "created_at"},
+	{"idx_content_miner_logs_miner", "content_miner_logs", "miner"},
+	{"idx_content_wallet_logs_created_at", "content_wallet_logs", "created_at"},
+	{"idx_delta_node_geo_locations_created_at", "delta_node_geo_locations", "created_at"},
+	{"idx_delta_startup_logs_created_at", "delta_startup_logs", "created_at"},
+	{"idx_instance_meta_logs_created_at", "instance_meta_logs", "created_at"},
+	{"idx_log_events_created_at", "log_events", "created_at"},
+	{"idx_piece_commitment_logs_created_at", "piece_commitment_logs", "created_at"},
+	{"idx_piece_commitment_logs_system_content_piece_commitment_id", "piece_commitment_logs", "system_content_piece_commitment_id"},
+	{"idx_wallet_logs_created_at", "wallet_logs", "created_at"},
+}
+
+// spProfileIndexes are the indexes created by the sp_profile_indexes migration
+var spProfileIndexes = []logIndex{
+	{"idx_content_deal_logs_miner", "content_deal_logs", "miner"},
+	{"idx_content_miner_logs_content", "content_miner_logs", "content"},
+}
+
+// retiredStatViews are the single value views replaced by mv_global_totals
+var retiredStatViews = []string{
+	"mv_deals_attempted", "mv_deals_attempted_past_24h", "mv_deals_attempted_size", "mv_deals_attempted_size_past_24h",
+	"mv_e2e_deals_attempted", "mv_e2e_deals_attempted_size", "mv_import_deals_attempted", "mv_import_deals_attempted_size",
+	"mv_deals_succeeded", "mv_deals_succeeded_past_24h", "mv_deals_succeeded_size", "mv_deals_succeeded_size_past_24h",
+	"mv_e2e_deals_succeeded", "mv_e2e_deals_succeeded_size", "mv_import_deals_succeeded", "mv_import_deals_succeeded_size",
+	"mv_commp_compute_succeeded", "mv_commp_compute_succeeded_size", "mv_commp_compute_attempted", "mv_commp_compute_attempted_size",
+	"mv_number_of_sps_work_with", "mv_number_of_unique_delta_nodes",
+	"mv_total_in_progress_deals_24", "mv_total_in_progress_e2e_deals_24", "mv_total_in_progress_import_deals_24",
+}
+
+// Migrations are the versioned schema changes, in version order. Versions must never be renumbered or reused.
+var Migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "base_tables",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&model.ContentDealLogs{},
+				&model.ContentDealProposalLogs{},
+				&model.ContentDealProposalParametersLogs{},
+				&model.ContentLogs{},
+				&model.ContentMinerLogs{},
+				&model.ContentWalletLogs{},
+				&model.DeltaNodeGeoLocations{},
+				&model.DeltaStartupLogs{},
+				&model.InstanceMetaLogs{},
+				&model.LogEvents{},
+				&model.PieceCommitmentLogs{},
+				&model.WalletLogs{},
+			).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return fmt.Errorf("%w: the log tables hold the data written by delta nodes, they are never dropped", ErrIrreversibleMigration)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "view_refresh_jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&RefreshJob{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.DropTableIfExists(&RefreshJob{}).Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "log_indexes",
+		Up: func(tx *gorm.DB) error {
+			return createIndexes(tx, logIndexes)
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropIndexes(tx, logIndexes)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "materialized_views",
+		// the views are created by the view sync following the migrations, from the registry of the running build.
+		// Creating them here would make this migration depend on view sql that keeps changing after it was applied.
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: dropViews,
+	},
+	{
+		Version: 5,
+		Name:    "global_totals_view",
+		// mv_global_totals itself is created by the view sync following the migrations
+		Up: func(tx *gorm.DB) error {
+			for _, name := range retiredStatViews {
+				if err := tx.Exec("DROP MATERIALIZED VIEW IF EXISTS " + name).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Where("name IN (?)", retiredStatViews).Delete(&SchemaView{}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return fmt.Errorf("%w: the sql of the retired views is no longer shipped, they cannot be created again", ErrIrreversibleMigration)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "sp_profile_indexes",
+		Up: func(tx *gorm.DB) error {
+			return createIndexes(tx, spProfileIndexes)
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropIndexes(tx, spProfileIndexes)
+		},
+	},
+}
+
+func createIndexes(tx *gorm.DB, indexes []logIndex) error {
+	for _, index := range indexes {
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS " + index.Name + " ON " + index.Table + " (" + index.Column + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropIndexes(tx *gorm.DB, indexes []logIndex) error {
+	for _, index := range indexes {
+		if err := tx.Exec("DROP INDEX IF EXISTS " + index.Name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
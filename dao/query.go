@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"reflect"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// ListQuery describes the page or cursor, ordering, filters, count mode and fields requested from a GetAll* function
+type ListQuery struct {
+	// Page page requested, 0 returns the first page
+	Page int64
+
+	// PageSize number of records in a page
+	PageSize int64
+
+	// Sort sort columns, see ParseSort
+	Sort []*SortField
+
+	// Filters column conditions, see ParseFilters
+	Filters []*Filter
+
+	// UseCursor pages with a keyset cursor instead of Page
+	UseCursor bool
+
+	// After values of the Sort columns to continue after, decoded from the cursor, empty for the first page
+	After []interface{}
+
+	// Count how the total record count is computed
+	Count CountMode
+
+	// Fields columns to select, empty selects every column, see ParseFields
+	Fields []string
+}
+
+// listRows runs query against db, a model scope of tableName, and stores the page in results, a pointer to a slice
+// of model pointers. nextCursor is set in cursor mode when there is another page.
+// error - ErrNotFound, db Count or Find error
+func listRows(db *gorm.DB, tableName string, query *ListQuery, results interface{}) (totalRows int, nextCursor string, err error) {
+	db = applyCursorScope(applyFilters(db, query.Filters), query)
+	if totalRows, err = countRows(db, tableName, query); err != nil {
+		return -1, "", ErrNotFound
+	}
+
+	if err = applyListFields(applyPage(db, query), query).Find(results).Error; err != nil {
+		return -1, "", ErrNotFound
+	}
+
+	rows := reflect.ValueOf(results).Elem()
+	if query.UseCursor && int64(rows.Len()) > query.PageSize {
+		rows.Set(rows.Slice(0, int(query.PageSize)))
+		last := rows.Index(rows.Len() - 1).Interface().(model.Model)
+		if nextCursor, err = EncodeCursor(last.TableInfo(), query, last); err != nil {
+			return -1, "", err
+		}
+	}
+
+	return totalRows, nextCursor, nil
+}
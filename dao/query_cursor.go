@@ -0,0 +1,239 @@
+package dao
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// CountMode controls how the total of a list request is computed
+type CountMode string
+
+const (
+	// CountExact runs count(*) with the request filters
+	CountExact = CountMode("exact")
+
+	// CountNone skips the count, the total is returned as -1
+	CountNone = CountMode("none")
+
+	// CountEstimate reads the planner estimate from pg_class.reltuples, only available without filters
+	CountEstimate = CountMode("estimate")
+
+	// keysetTimeColumn is the column used with the primary key for cursor pagination
+	keysetTimeColumn = "created_at"
+)
+
+// cursorPosition is the decoded form of the opaque cursor returned as nextCursor
+type cursorPosition struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// ParseCountMode parses the count query parameter, empty or true is an exact count and false skips it
+func ParseCountMode(s string) (CountMode, error) {
+	switch strings.ToLower(s) {
+	case "", "true", string(CountExact):
+		return CountExact, nil
+	case "false", string(CountNone):
+		return CountNone, nil
+	case string(CountEstimate):
+		return CountEstimate, nil
+	default:
+		return CountExact, fmt.Errorf("%w: count must be one of true, false or estimate", ErrBadParams)
+	}
+}
+
+// DefaultKeysetSort is the cursor pagination order used when the request does not set one,
+// newest first on (created_at, id) when the table has created_at, otherwise the primary key.
+func DefaultKeysetSort(tableInfo *model.TableInfo) []*SortField {
+	if findColumn(tableInfo, keysetTimeColumn) != nil {
+		fields := []*SortField{{Column: keysetTimeColumn, Desc: true}}
+		for _, column := range tableInfo.Columns {
+			if column.IsPrimaryKey {
+				fields = append(fields, &SortField{Column: column.Name, Desc: true})
+			}
+		}
+		return fields
+	}
+
+	sort, _ := ParseSort(tableInfo, "", "")
+	return sort
+}
+
+// ParseCursor validates that sort can be used for cursor pagination and decodes cursor, an empty cursor starts at the first page.
+// error - ErrBadParams, sort on a column other than created_at and the primary key, or a cursor that is corrupt or from a different sort
+func ParseCursor(tableInfo *model.TableInfo, sort []*SortField, cursor string) ([]interface{}, error) {
+	for _, field := range sort {
+		column := findColumn(tableInfo, field.Column)
+		if column == nil || !(column.IsPrimaryKey || column.Name == keysetTimeColumn) {
+			return nil, fmt.Errorf("%w: cursor pagination can only sort on %s and the primary key", ErrBadParams, keysetTimeColumn)
+		}
+	}
+
+	if cursor == "" {
+		return nil, nil
+	}
+
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrBadParams)
+	}
+
+	position := &cursorPosition{}
+	if err = json.Unmarshal(buf, position); err != nil || len(position.Values) != len(sort) {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrBadParams)
+	}
+
+	if position.Sort != sortSignature(sort) {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrBadParams)
+	}
+
+	values := make([]interface{}, len(sort))
+	for i, field := range sort {
+		raw, ok := position.Values[i].(string)
+		if !ok {
+			raw = fmt.Sprint(position.Values[i])
+		}
+
+		if values[i], err = parseColumnValue(findColumn(tableInfo, field.Column), raw); err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrBadParams)
+		}
+	}
+
+	return values, nil
+}
+
+// EncodeCursor builds the opaque cursor that continues a cursor paged query after record
+func EncodeCursor(tableInfo *model.TableInfo, query *ListQuery, record interface{}) (string, error) {
+	position := &cursorPosition{Sort: sortSignature(query.Sort)}
+
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	for _, field := range query.Sort {
+		column := findColumn(tableInfo, field.Column)
+		if column == nil {
+			return "", fmt.Errorf("unknown cursor column %s", field.Column)
+		}
+
+		value := rv.FieldByName(column.GoFieldName).Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil {
+				return "", err
+			}
+		}
+
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		position.Values = append(position.Values, fmt.Sprint(value))
+	}
+
+	buf, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// countRows counts the rows matched by db according to query.Count, returning -1 when the count is skipped
+func countRows(db *gorm.DB, tableName string, query *ListQuery) (totalRows int, err error) {
+	switch query.Count {
+	case CountNone:
+		return -1, nil
+
+	case CountEstimate:
+		if len(query.Filters) > 0 {
+			return -1, nil
+		}
+
+		var estimate float64
+		row := DB.Raw("SELECT reltuples FROM pg_class WHERE relname = ?", tableName).Row()
+		if err = row.Scan(&estimate); err != nil || estimate < 0 {
+			return -1, nil
+		}
+		return int(estimate), nil
+
+	default:
+		err = db.Count(&totalRows).Error
+		return totalRows, err
+	}
+}
+
+// applyCursorScope leaves out the rows without created_at when query pages on it with a cursor, they cannot be
+// positioned by a cursor. It is applied before the count so the total matches the rows that can be paged through.
+func applyCursorScope(db *gorm.DB, query *ListQuery) *gorm.DB {
+	if !query.UseCursor {
+		return db
+	}
+
+	for _, field := range query.Sort {
+		if field.Column == keysetTimeColumn {
+			return db.Where(quoteColumn(keysetTimeColumn) + " IS NOT NULL")
+		}
+	}
+	return db
+}
+
+// applyPage adds the sort order and either the offset or the cursor position of query to db, see applyCursorScope.
+// In cursor mode one extra row is requested so the caller can tell whether there is a next page.
+func applyPage(db *gorm.DB, query *ListQuery) *gorm.DB {
+	if !query.UseCursor {
+		if offset := pageOffset(query.Page, query.PageSize); offset > 0 {
+			db = db.Offset(offset)
+		}
+		return applySort(db.Limit(query.PageSize), query.Sort)
+	}
+
+	if len(query.After) == len(query.Sort) && len(query.After) > 0 {
+		// (a > x) OR (a = x AND b > y) OR ..., which supports mixed directions unlike a row comparison
+		var terms []string
+		var args []interface{}
+		for i, field := range query.Sort {
+			var parts []string
+			for j := 0; j < i; j++ {
+				parts = append(parts, quoteColumn(query.Sort[j].Column)+" = ?")
+				args = append(args, query.After[j])
+			}
+
+			op := " > ?"
+			if field.Desc {
+				op = " < ?"
+			}
+			parts = append(parts, quoteColumn(field.Column)+op)
+			args = append(args, query.After[i])
+
+			terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+		}
+		db = db.Where(strings.Join(terms, " OR "), args...)
+	}
+
+	return applySort(db, query.Sort).Limit(query.PageSize + 1)
+}
+
+// pageOffset is the number of rows before page, pages are numbered from 1 and page 0 is the first page too
+func pageOffset(page, pageSize int64) int64 {
+	if page <= 1 {
+		return 0
+	}
+	return (page - 1) * pageSize
+}
+
+func sortSignature(sort []*SortField) string {
+	terms := make([]string, len(sort))
+	for i, field := range sort {
+		if field.Desc {
+			terms[i] = "-" + field.Column
+		} else {
+			terms[i] = field.Column
+		}
+	}
+	return strings.Join(terms, ",")
+}
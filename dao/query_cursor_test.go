@@ -0,0 +1,228 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// errRecorded is returned for every statement sent to a recordingDB
+var errRecorded = errors.New("statement recorded, not run")
+
+// recordingDB is a gorm.SQLCommon that records the statements it is sent and fails them, QueryRow is not supported
+type recordingDB struct {
+	statements []string
+	args       [][]interface{}
+}
+
+func (d *recordingDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	d.record(query, args)
+	return nil, errRecorded
+}
+
+func (d *recordingDB) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errRecorded
+}
+
+func (d *recordingDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	d.record(query, args)
+	return nil, errRecorded
+}
+
+func (d *recordingDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	panic("recordingDB does not support QueryRow")
+}
+
+func (d *recordingDB) record(query string, args []interface{}) {
+	d.statements = append(d.statements, query)
+	d.args = append(d.args, args)
+}
+
+// openRecordingDB returns a postgres gorm.DB sending its statements to a recordingDB
+func openRecordingDB(t *testing.T) (*gorm.DB, *recordingDB) {
+	t.Helper()
+
+	rec := &recordingDB{}
+	db, err := gorm.Open("postgres", rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.LogMode(false)
+	return db, rec
+}
+
+// cursorRecord is a row of testTableInfo
+type cursorRecord struct {
+	ID        int64
+	CreatedAt time.Time
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 12, 30, 15, 123456789, time.UTC)
+	record := &cursorRecord{ID: 42, CreatedAt: createdAt}
+
+	tests := []struct {
+		name string
+		sort string
+		want []interface{}
+	}{
+		{"default keyset", "-created_at,-id", []interface{}{createdAt, int64(42)}},
+		{"mixed directions", "created_at,-id", []interface{}{createdAt, int64(42)}},
+		{"primary key only", "id", []interface{}{int64(42)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := ParseSort(testTableInfo(), "", tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cursor, err := EncodeCursor(testTableInfo(), &ListQuery{Sort: sort}, record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseCursor(testTableInfo(), sort, cursor)
+			if err != nil {
+				t.Fatalf("ParseCursor(%q) error %v", cursor, err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseCursor() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if want, ok := tt.want[i].(time.Time); ok {
+					if v, ok := got[i].(time.Time); !ok || !v.Equal(want) {
+						t.Errorf("value %d = %#v, want %v", i, got[i], want)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("value %d = %#v, want %#v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseCursorErrors(t *testing.T) {
+	sort := DefaultKeysetSort(testTableInfo())
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	valid, err := EncodeCursor(testTableInfo(), &ListQuery{Sort: sort}, &cursorRecord{ID: 1, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ascending, err := ParseSort(testTableInfo(), "", "created_at,id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		sort   []*SortField
+		cursor string
+		err    string
+	}{
+		{"not base64", sort, "!!!", "invalid cursor"},
+		{"not json", sort, encode("garbage"), "invalid cursor"},
+		{"missing values", sort, encode(`{"s":"-created_at,-id","v":["2023-04-01"]}`), "invalid cursor"},
+		{"tampered time", sort, encode(`{"s":"-created_at,-id","v":["yesterday","1"]}`), "invalid cursor"},
+		{"tampered id", sort, encode(`{"s":"-created_at,-id","v":["2023-04-01","1 OR 1=1"]}`), "invalid cursor"},
+		{"tampered sort", sort, encode(`{"s":"-created_at,id","v":["2023-04-01","1"]}`), "cursor was issued for a different sort order"},
+		{"other sort", ascending, valid, "cursor was issued for a different sort order"},
+		{"sort not keyset", []*SortField{{Column: "status"}, {Column: "id"}}, "", "cursor pagination can only sort on created_at and the primary key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := ParseCursor(testTableInfo(), tt.sort, tt.cursor)
+			if err == nil {
+				t.Fatalf("ParseCursor(%q) = %v, want an error", tt.cursor, values)
+			}
+			if !errors.Is(err, ErrBadParams) {
+				t.Errorf("ParseCursor(%q) error %v is not ErrBadParams", tt.cursor, err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseCursor(%q) error %q, want it to contain %q", tt.cursor, err, tt.err)
+			}
+		})
+	}
+
+	if values, err := ParseCursor(testTableInfo(), sort, ""); err != nil || values != nil {
+		t.Errorf("ParseCursor(\"\") = %v, %v, want the first page", values, err)
+	}
+}
+
+func TestCursorPageSQL(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query *ListQuery
+		sql   string
+		args  []interface{}
+	}{
+		{
+			name:  "offset page",
+			query: &ListQuery{Page: 3, PageSize: 10, Sort: []*SortField{{Column: "id"}}},
+			sql:   `SELECT * FROM "deals" ORDER BY "id" ASC LIMIT 10 OFFSET 20`,
+		},
+		{
+			name:  "first cursor page",
+			query: &ListQuery{UseCursor: true, PageSize: 10, Sort: []*SortField{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}},
+			sql:   `SELECT * FROM "deals" WHERE ("created_at" IS NOT NULL) ORDER BY "created_at" DESC,"id" DESC LIMIT 11`,
+		},
+		{
+			// rows sharing the cursor created_at are continued on the id tiebreaker
+			name: "next cursor page",
+			query: &ListQuery{UseCursor: true, PageSize: 10, Sort: []*SortField{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}},
+				After: []interface{}{createdAt, int64(7)}},
+			sql:  `SELECT * FROM "deals" WHERE ("created_at" IS NOT NULL) AND (("created_at" < $1) OR ("created_at" = $2 AND "id" < $3)) ORDER BY "created_at" DESC,"id" DESC LIMIT 11`,
+			args: []interface{}{createdAt, createdAt, int64(7)},
+		},
+		{
+			name: "mixed directions",
+			query: &ListQuery{UseCursor: true, PageSize: 5, Sort: []*SortField{{Column: "created_at"}, {Column: "id", Desc: true}},
+				After: []interface{}{createdAt, int64(7)}},
+			sql:  `SELECT * FROM "deals" WHERE ("created_at" IS NOT NULL) AND (("created_at" > $1) OR ("created_at" = $2 AND "id" < $3)) ORDER BY "created_at" ASC,"id" DESC LIMIT 6`,
+			args: []interface{}{createdAt, createdAt, int64(7)},
+		},
+		{
+			name:  "primary key cursor keeps rows without created_at",
+			query: &ListQuery{UseCursor: true, PageSize: 10, Sort: []*SortField{{Column: "id"}}, After: []interface{}{int64(7)}},
+			sql:   `SELECT * FROM "deals" WHERE (("id" > $1)) ORDER BY "id" ASC LIMIT 11`,
+			args:  []interface{}{int64(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, rec := openRecordingDB(t)
+
+			var rows []*cursorRecord
+			applyPage(applyCursorScope(db.Table("deals"), tt.query), tt.query).Find(&rows)
+			if len(rec.statements) != 1 {
+				t.Fatalf("ran %d statements, want 1", len(rec.statements))
+			}
+
+			if got := strings.Join(strings.Fields(rec.statements[0]), " "); got != tt.sql {
+				t.Errorf("sql = %s\nwant %s", got, tt.sql)
+			}
+			if len(rec.args[0]) != len(tt.args) {
+				t.Fatalf("args = %v, want %v", rec.args[0], tt.args)
+			}
+			for i := range tt.args {
+				if !reflect.DeepEqual(rec.args[0][i], tt.args[i]) {
+					t.Errorf("arg %d = %#v, want %#v", i, rec.args[0][i], tt.args[i])
+				}
+			}
+		})
+	}
+}
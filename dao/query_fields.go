@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// ParseFields parses a comma separated fields parameter against the columns of a table, an empty value selects every column.
+// error - ErrBadParams, unknown column
+func ParseFields(tableInfo *model.TableInfo, fields string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(fields, ",") {
+		if name = strings.TrimSpace(name); name == "" || seen[name] {
+			continue
+		}
+
+		if findColumn(tableInfo, name) == nil {
+			return nil, fmt.Errorf("%w: unknown field %q, allowed fields are %s", ErrBadParams, name, strings.Join(columnNames(tableInfo), ", "))
+		}
+
+		seen[name] = true
+		columns = append(columns, name)
+	}
+
+	return columns, nil
+}
+
+// applyFields restricts the select list of db to fields plus any extra columns needed by the query, e.g. cursor sort columns
+func applyFields(db *gorm.DB, fields []string, extra ...string) *gorm.DB {
+	if len(fields) == 0 {
+		return db
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	// a new slice, appending to fields could write into the backing array of the caller
+	for _, name := range append(append([]string{}, fields...), extra...) {
+		if !seen[name] {
+			seen[name] = true
+			columns = append(columns, quoteColumn(name))
+		}
+	}
+
+	return db.Select(strings.Join(columns, ", "))
+}
+
+// applyListFields restricts the select list of db to the fields of q, keeping the sort columns needed to build a cursor
+func applyListFields(db *gorm.DB, q *ListQuery) *gorm.DB {
+	if !q.UseCursor {
+		return applyFields(db, q.Fields)
+	}
+
+	sortColumns := make([]string, len(q.Sort))
+	for i, field := range q.Sort {
+		sortColumns[i] = field.Column
+	}
+	return applyFields(db, q.Fields, sortColumns...)
+}
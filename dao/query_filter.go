@@ -0,0 +1,245 @@
+package dao
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// FilterOp is a comparison operator used in list endpoint filters, e.g. ?status=eq:transfer-failed
+type FilterOp string
+
+const (
+	FilterEq     = FilterOp("eq")
+	FilterNe     = FilterOp("ne")
+	FilterLt     = FilterOp("lt")
+	FilterLte    = FilterOp("lte")
+	FilterGt     = FilterOp("gt")
+	FilterGte    = FilterOp("gte")
+	FilterIn     = FilterOp("in")
+	FilterNotIn  = FilterOp("nin")
+	FilterLike   = FilterOp("like")
+	FilterILike  = FilterOp("ilike")
+	FilterIsNull = FilterOp("isnull")
+
+	// maxFilterInValues caps the number of values accepted by in/nin
+	maxFilterInValues = 100
+)
+
+var (
+	filterOpSQL = map[FilterOp]string{
+		FilterEq:    "=",
+		FilterNe:    "<>",
+		FilterLt:    "<",
+		FilterLte:   "<=",
+		FilterGt:    ">",
+		FilterGte:   ">=",
+		FilterIn:    "IN",
+		FilterNotIn: "NOT IN",
+		FilterLike:  "LIKE",
+		FilterILike: "ILIKE",
+	}
+
+	// filterOpsByType lists the operators allowed for each DatabaseTypeName
+	filterOpsByType = map[string][]FilterOp{
+		"INT8":        {FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte, FilterIn, FilterNotIn, FilterIsNull},
+		"NUMERIC":     {FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte, FilterIn, FilterNotIn, FilterIsNull},
+		"TIMESTAMPTZ": {FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte, FilterIsNull},
+		"TEXT":        {FilterEq, FilterNe, FilterIn, FilterNotIn, FilterLike, FilterILike, FilterIsNull},
+		"BOOL":        {FilterEq, FilterNe, FilterIsNull},
+		"BYTEA":       {FilterIsNull},
+	}
+
+	filterTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+)
+
+// Filter is a single validated column condition
+type Filter struct {
+	Column string
+	Op     FilterOp
+	Value  interface{}
+}
+
+// ParseFilters parses the filter query parameters of a list request against the columns of a table.
+// Parameters named in reserved (page, pagesize, ...) are skipped, any other parameter must be a column.
+// A value without an operator prefix is an eq match, e.g. ?status=transfer-failed
+// error - ErrBadParams, unknown column, operator not allowed for the column type or unparsable value
+func ParseFilters(tableInfo *model.TableInfo, values url.Values, reserved ...string) ([]*Filter, error) {
+	skip := make(map[string]bool, len(reserved))
+	for _, name := range reserved {
+		skip[name] = true
+	}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		if !skip[param] {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	var filters []*Filter
+	for _, param := range params {
+		column := findColumn(tableInfo, param)
+		if column == nil {
+			return nil, fmt.Errorf("%w: unknown filter column %q, allowed columns are %s", ErrBadParams, param, strings.Join(columnNames(tableInfo), ", "))
+		}
+
+		for _, raw := range values[param] {
+			filter, err := parseFilter(column, raw)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters, nil
+}
+
+func parseFilter(column *model.ColumnInfo, raw string) (*Filter, error) {
+	op, value := FilterEq, raw
+	if i := strings.Index(raw, ":"); i > 0 {
+		candidate := FilterOp(strings.ToLower(raw[:i]))
+		if _, ok := filterOpSQL[candidate]; ok || candidate == FilterIsNull {
+			op, value = candidate, raw[i+1:]
+		}
+	}
+
+	if !filterOpAllowed(column, op) {
+		return nil, fmt.Errorf("%w: operator %s not allowed on %s column %s", ErrBadParams, op, column.DatabaseTypeName, column.Name)
+	}
+
+	filter := &Filter{Column: column.Name, Op: op}
+	switch op {
+	case FilterIsNull:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: isnull on %s expects true or false", ErrBadParams, column.Name)
+		}
+		filter.Value = isNull
+
+	case FilterIn, FilterNotIn:
+		parts := strings.Split(value, ",")
+		if len(parts) > maxFilterInValues {
+			return nil, fmt.Errorf("%w: %s on %s accepts at most %d values", ErrBadParams, op, column.Name, maxFilterInValues)
+		}
+		list := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			v, err := parseColumnValue(column, part)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		filter.Value = list
+
+	default:
+		v, err := parseColumnValue(column, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Value = v
+	}
+
+	return filter, nil
+}
+
+func filterOpAllowed(column *model.ColumnInfo, op FilterOp) bool {
+	if op == FilterIsNull && !column.Nullable {
+		return false
+	}
+
+	for _, allowed := range filterOpsByType[column.DatabaseTypeName] {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseColumnValue converts a query string value to the go type matching the column
+func parseColumnValue(column *model.ColumnInfo, value string) (interface{}, error) {
+	switch column.DatabaseTypeName {
+	case "INT8":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects an integer, got %q", ErrBadParams, column.Name, value)
+		}
+		return v, nil
+
+	case "NUMERIC":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects a number, got %q", ErrBadParams, column.Name, value)
+		}
+		return v, nil
+
+	case "BOOL":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects true or false, got %q", ErrBadParams, column.Name, value)
+		}
+		return v, nil
+
+	case "TIMESTAMPTZ":
+		for _, layout := range filterTimeLayouts {
+			if v, err := time.Parse(layout, value); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s expects an RFC3339 time or a YYYY-MM-DD date, got %q", ErrBadParams, column.Name, value)
+
+	default:
+		return value, nil
+	}
+}
+
+// applyFilters adds the filters to db as parameterised where clauses
+func applyFilters(db *gorm.DB, filters []*Filter) *gorm.DB {
+	for _, filter := range filters {
+		column := quoteColumn(filter.Column)
+		switch filter.Op {
+		case FilterIsNull:
+			if filter.Value.(bool) {
+				db = db.Where(column + " IS NULL")
+			} else {
+				db = db.Where(column + " IS NOT NULL")
+			}
+		case FilterIn, FilterNotIn:
+			db = db.Where(fmt.Sprintf("%s %s (?)", column, filterOpSQL[filter.Op]), filter.Value)
+		default:
+			db = db.Where(fmt.Sprintf("%s %s ?", column, filterOpSQL[filter.Op]), filter.Value)
+		}
+	}
+
+	return db
+}
+
+func findColumn(tableInfo *model.TableInfo, name string) *model.ColumnInfo {
+	for _, column := range tableInfo.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+func columnNames(tableInfo *model.TableInfo) []string {
+	names := make([]string, 0, len(tableInfo.Columns))
+	for _, column := range tableInfo.Columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// quoteColumn quotes a column name that has already been checked against the table info
+func quoteColumn(name string) string {
+	return `"` + name + `"`
+}
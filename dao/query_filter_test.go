@@ -0,0 +1,135 @@
+package dao
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/application-research/delta-metrics-rest/model"
+)
+
+// testTableInfo is a table with a column of each filterable type
+func testTableInfo() *model.TableInfo {
+	return &model.TableInfo{
+		Name: "deals",
+		Columns: []*model.ColumnInfo{
+			{Name: "id", GoFieldName: "ID", DatabaseTypeName: "INT8", IsPrimaryKey: true},
+			{Name: "created_at", GoFieldName: "CreatedAt", DatabaseTypeName: "TIMESTAMPTZ", Nullable: true},
+			{Name: "status", GoFieldName: "Status", DatabaseTypeName: "TEXT", Nullable: true},
+			{Name: "size", GoFieldName: "Size", DatabaseTypeName: "NUMERIC"},
+			{Name: "verified", GoFieldName: "Verified", DatabaseTypeName: "BOOL"},
+			{Name: "payload", GoFieldName: "Payload", DatabaseTypeName: "BYTEA", Nullable: true},
+		},
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	day := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query string
+		want  []*Filter
+	}{
+		{"none", "", nil},
+		{"default eq", "status=transfer-failed", []*Filter{{"status", FilterEq, "transfer-failed"}}},
+		{"explicit eq", "status=eq:transfer-failed", []*Filter{{"status", FilterEq, "transfer-failed"}}},
+		{"uppercase operator", "status=NE:sealed", []*Filter{{"status", FilterNe, "sealed"}}},
+		{"unknown operator prefix is part of the value", "status=error:timeout", []*Filter{{"status", FilterEq, "error:timeout"}}},
+		{"leading colon is part of the value", "status=:x", []*Filter{{"status", FilterEq, ":x"}}},
+		{"like", "status=like:transfer%25", []*Filter{{"status", FilterLike, "transfer%"}}},
+		{"ilike", "status=ilike:SEALED", []*Filter{{"status", FilterILike, "SEALED"}}},
+		{"text in", "status=in:a,b", []*Filter{{"status", FilterIn, []interface{}{"a", "b"}}}},
+		{"int", "id=gte:10", []*Filter{{"id", FilterGte, int64(10)}}},
+		{"int not in", "id=nin:1,2", []*Filter{{"id", FilterNotIn, []interface{}{int64(1), int64(2)}}}},
+		{"numeric", "size=lt:1.5", []*Filter{{"size", FilterLt, 1.5}}},
+		{"bool", "verified=true", []*Filter{{"verified", FilterEq, true}}},
+		{"date", "created_at=gt:2023-04-01", []*Filter{{"created_at", FilterGt, day}}},
+		{"time without zone", "created_at=lte:2023-04-01T00:00:00", []*Filter{{"created_at", FilterLte, day}}},
+		{"rfc3339", "created_at=2023-04-01T02:00:00%2B02:00", []*Filter{{"created_at", FilterEq, day}}},
+		{"isnull", "payload=isnull:true", []*Filter{{"payload", FilterIsNull, true}}},
+		{"is not null", "status=isnull:false", []*Filter{{"status", FilterIsNull, false}}},
+		{"reserved params are skipped", "page=2&pagesize=10&order=id&id=1", []*Filter{{"id", FilterEq, int64(1)}}},
+		{"sorted by column, repeated params kept in order", "status=ne:a&id=gt:1&status=ne:b", []*Filter{
+			{"id", FilterGt, int64(1)},
+			{"status", FilterNe, "a"},
+			{"status", FilterNe, "b"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseFilters(testTableInfo(), values, "page", "pagesize", "order")
+			if err != nil {
+				t.Fatalf("ParseFilters(%q) error %v", tt.query, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseFilters(%q) = %d filters, want %d", tt.query, len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Column != tt.want[i].Column || got[i].Op != tt.want[i].Op {
+					t.Errorf("filter %d = %s %s, want %s %s", i, got[i].Column, got[i].Op, tt.want[i].Column, tt.want[i].Op)
+				}
+				if want, ok := tt.want[i].Value.(time.Time); ok {
+					if v, ok := got[i].Value.(time.Time); !ok || !v.Equal(want) {
+						t.Errorf("filter %d value = %#v, want %v", i, got[i].Value, want)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got[i].Value, tt.want[i].Value) {
+					t.Errorf("filter %d value = %#v, want %#v", i, got[i].Value, tt.want[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFiltersErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"unknown column", "owner=x", `unknown filter column "owner"`},
+		{"reserved name not passed as reserved", "page=1", `unknown filter column "page"`},
+		{"like on int", "id=like:1%25", "operator like not allowed on INT8 column id"},
+		{"range on text", "status=gt:a", "operator gt not allowed on TEXT column status"},
+		{"in on time", "created_at=in:2023-04-01", "operator in not allowed on TIMESTAMPTZ column created_at"},
+		{"eq on bytea", "payload=x", "operator eq not allowed on BYTEA column payload"},
+		{"isnull on not null column", "size=isnull:true", "operator isnull not allowed on NUMERIC column size"},
+		{"isnull value", "status=isnull:maybe", "isnull on status expects true or false"},
+		{"int value", "id=abc", `id expects an integer, got "abc"`},
+		{"int in value", "id=in:1,x", `id expects an integer, got "x"`},
+		{"numeric value", "size=gt:big", `size expects a number, got "big"`},
+		{"bool value", "verified=yes", `verified expects true or false, got "yes"`},
+		{"time value", "created_at=gt:yesterday", `created_at expects an RFC3339 time`},
+		{"too many in values", "id=in:" + strings.Repeat("1,", maxFilterInValues) + "1", "in on id accepts at most 100 values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filters, err := ParseFilters(testTableInfo(), values)
+			if err == nil {
+				t.Fatalf("ParseFilters(%q) = %v, want an error", tt.query, filters)
+			}
+			if !errors.Is(err, ErrBadParams) {
+				t.Errorf("ParseFilters(%q) error %v is not ErrBadParams", tt.query, err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseFilters(%q) error %q, want it to contain %q", tt.query, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/application-research/delta-metrics-rest/model"
+	"github.com/jinzhu/gorm"
+)
+
+// SortField is a single validated sort column
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSort parses the order of a list request against the columns of a table.
+// order takes the form "created_at desc,id asc" and sort the form "-created_at,id", only one of them may be set.
+// The primary key is appended as a tie-breaker so that paging through equal values is stable.
+// error - ErrBadParams, unknown or repeated column, unknown direction or both order and sort set
+func ParseSort(tableInfo *model.TableInfo, order, sort string) ([]*SortField, error) {
+	if order != "" && sort != "" {
+		return nil, fmt.Errorf("%w: use either order or sort, not both", ErrBadParams)
+	}
+
+	var fields []*SortField
+	seen := make(map[string]bool)
+	for _, term := range splitSortTerms(order + sort) {
+		field, err := parseSortTerm(term, sort != "")
+		if err != nil {
+			return nil, err
+		}
+
+		if findColumn(tableInfo, field.Column) == nil {
+			return nil, fmt.Errorf("%w: unknown sort column %q, allowed columns are %s", ErrBadParams, field.Column, strings.Join(columnNames(tableInfo), ", "))
+		}
+
+		if seen[field.Column] {
+			return nil, fmt.Errorf("%w: sort column %q repeated", ErrBadParams, field.Column)
+		}
+		seen[field.Column] = true
+
+		fields = append(fields, field)
+	}
+
+	for _, column := range tableInfo.Columns {
+		if column.IsPrimaryKey && !seen[column.Name] {
+			fields = append(fields, &SortField{Column: column.Name})
+		}
+	}
+
+	return fields, nil
+}
+
+func splitSortTerms(s string) []string {
+	var terms []string
+	for _, term := range strings.Split(s, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// parseSortTerm parses "column [asc|desc]", or "[-]column" when prefixed is set
+func parseSortTerm(term string, prefixed bool) (*SortField, error) {
+	if prefixed {
+		if strings.HasPrefix(term, "-") {
+			return &SortField{Column: strings.TrimPrefix(term, "-"), Desc: true}, nil
+		}
+		return &SortField{Column: strings.TrimPrefix(term, "+")}, nil
+	}
+
+	parts := strings.Fields(term)
+	field := &SortField{Column: parts[0]}
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && strings.EqualFold(parts[1], "asc"):
+	case len(parts) == 2 && strings.EqualFold(parts[1], "desc"):
+		field.Desc = true
+	default:
+		return nil, fmt.Errorf("%w: invalid order term %q, expected \"column asc\" or \"column desc\"", ErrBadParams, term)
+	}
+
+	return field, nil
+}
+
+// applySort adds the sort fields to db as order clauses
+func applySort(db *gorm.DB, fields []*SortField) *gorm.DB {
+	for _, field := range fields {
+		if field.Desc {
+			db = db.Order(quoteColumn(field.Column) + " DESC")
+		} else {
+			db = db.Order(quoteColumn(field.Column) + " ASC")
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		order string
+		sort  string
+		want  string
+	}{
+		{"default is the primary key", "", "", "id"},
+		{"order ascending by default", "status", "", "status,id"},
+		{"order directions", "created_at DESC, status asc", "", "-created_at,status,id"},
+		{"order on the primary key", "id desc", "", "-id"},
+		{"order skips empty terms", "status, ,created_at", "", "status,created_at,id"},
+		{"sort prefixes", "", "-created_at,+status", "-created_at,status,id"},
+		{"sort on the primary key", "", "-id,status", "-id,status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseSort(testTableInfo(), tt.order, tt.sort)
+			if err != nil {
+				t.Fatalf("ParseSort(%q, %q) error %v", tt.order, tt.sort, err)
+			}
+			if got := sortSignature(fields); got != tt.want {
+				t.Errorf("ParseSort(%q, %q) = %s, want %s", tt.order, tt.sort, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		order string
+		sort  string
+		err   string
+	}{
+		{"order and sort", "status", "-id", "use either order or sort, not both"},
+		{"unknown order column", "owner desc", "", `unknown sort column "owner"`},
+		{"unknown sort column", "", "-owner", `unknown sort column "owner"`},
+		{"direction in sort", "", "status desc", `unknown sort column "status desc"`},
+		{"repeated order column", "status asc,status desc", "", `sort column "status" repeated`},
+		{"repeated sort column", "", "status,-status", `sort column "status" repeated`},
+		{"unknown direction", "status up", "", `invalid order term "status up"`},
+		{"extra words", "status asc nulls", "", `invalid order term "status asc nulls"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseSort(testTableInfo(), tt.order, tt.sort)
+			if err == nil {
+				t.Fatalf("ParseSort(%q, %q) = %s, want an error", tt.order, tt.sort, sortSignature(fields))
+			}
+			if !errors.Is(err, ErrBadParams) {
+				t.Errorf("ParseSort(%q, %q) error %v is not ErrBadParams", tt.order, tt.sort, err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseSort(%q, %q) error %q, want it to contain %q", tt.order, tt.sort, err, tt.err)
+			}
+		})
+	}
+}
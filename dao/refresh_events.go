@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"log"
+	"sync"
+)
+
+// RefreshEvent is published when a view refresh finishes
+type RefreshEvent struct {
+	// Job copy of the finished job
+	Job RefreshJob
+
+	// Views names of the views the job refreshed
+	Views []string
+
+	// Refreshed names of the views whose refresh statement succeeded
+	Refreshed []string
+}
+
+var refreshListeners struct {
+	sync.RWMutex
+	listeners []func(event *RefreshEvent)
+}
+
+// OnRefreshComplete registers fn to be called after every view refresh, on the goroutine that ran the refresh
+func OnRefreshComplete(fn func(event *RefreshEvent)) {
+	refreshListeners.Lock()
+	defer refreshListeners.Unlock()
+
+	refreshListeners.listeners = append(refreshListeners.listeners, fn)
+}
+
+// publishRefreshEvent calls the listeners, a panicking listener is logged and does not stop the others
+func publishRefreshEvent(job *RefreshJob, views []*MaterializedView) {
+	event := &RefreshEvent{Job: *job}
+	for _, view := range views {
+		event.Views = append(event.Views, view.Name)
+	}
+	event.Refreshed = refreshedViews(job, event.Views)
+
+	refreshListeners.RLock()
+	listeners := make([]func(event *RefreshEvent), len(refreshListeners.listeners))
+	copy(listeners, refreshListeners.listeners)
+	refreshListeners.RUnlock()
+
+	for _, listener := range listeners {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("refresh listener failed for job %s, the error is '%v'", job.JobID, r)
+				}
+			}()
+			listener(event)
+		}()
+	}
+}
+
+// refreshedViews returns the views of the finished job whose statement succeeded, views are in statement order.
+// A refresh aborted by a panic counts the statement it was running as failed.
+func refreshedViews(job *RefreshJob, views []string) []string {
+	failed := make(map[int]bool, len(job.Errors))
+	for _, e := range job.Errors {
+		if e.Statement == "" {
+			failed[job.Statements-1] = true
+			continue
+		}
+		failed[e.Index] = true
+	}
+
+	var refreshed []string
+	for i, view := range views {
+		if i < job.Statements && !failed[i] {
+			refreshed = append(refreshed, view)
+		}
+	}
+	return refreshed
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRefreshedViews(t *testing.T) {
+	views := []string{"a", "b", "c"}
+	tests := []struct {
+		name       string
+		statements int
+		errs       []RefreshStatementError
+		want       string
+	}{
+		{"succeeded", 3, nil, "a,b,c"},
+		{"partial", 3, []RefreshStatementError{{Index: 1, Statement: "REFRESH b", Error: "boom"}}, "a,c"},
+		{"failed", 3, []RefreshStatementError{
+			{Index: 0, Statement: "REFRESH a", Error: "boom"},
+			{Index: 1, Statement: "REFRESH b", Error: "boom"},
+			{Index: 2, Statement: "REFRESH c", Error: "boom"},
+		}, ""},
+		{"aborted", 2, []RefreshStatementError{{Error: "refresh aborted: boom"}}, "a"},
+		{"not started", 0, []RefreshStatementError{{Statement: "pg_try_advisory_lock", Error: "boom"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &RefreshJob{Statements: tt.statements, Errors: tt.errs}
+			if got := strings.Join(refreshedViews(job, views), ","); got != tt.want {
+				t.Errorf("refreshedViews() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// RefreshTriggerScheduler refresh started by the background scheduler
+	RefreshTriggerScheduler = "scheduler"
+
+	// RefreshTriggerAdmin refresh started through the admin api
+	RefreshTriggerAdmin = "admin"
+
+	// RefreshStatusRunning the refresh has not finished yet
+	RefreshStatusRunning = "running"
+
+	// RefreshStatusSucceeded every statement of the refresh succeeded
+	RefreshStatusSucceeded = "succeeded"
+
+	// RefreshStatusPartial some statements of the refresh failed, the others succeeded
+	RefreshStatusPartial = "partial"
+
+	// RefreshStatusFailed no statement of the refresh succeeded
+	RefreshStatusFailed = "failed"
+
+	// RefreshStatusDeferred the refresh did not run because another instance was refreshing the same view group
+	RefreshStatusDeferred = "deferred"
+
+	// RefreshStatusInterrupted the process stopped while the refresh was running
+	RefreshStatusInterrupted = "interrupted"
+
+	// MaxRefreshHistory caps the number of runs returned by GetRefreshJobHistory
+	MaxRefreshHistory = 100
+)
+
+var (
+	// ErrRefreshInProgress error when this process is already refreshing the same view or view group
+	ErrRefreshInProgress = fmt.Errorf("refresh already in progress")
+
+	// refreshJobs tracks the running refresh of each view
+	refreshJobs = &refreshJobTracker{running: make(map[string]*RefreshJob)}
+)
+
+// RefreshStatementError is a statement of a refresh that failed
+type RefreshStatementError struct {
+	// Index position of the statement in the refresh script
+	Index     int    `json:"index"`
+	Statement string `json:"statement"`
+	Error     string `json:"error"`
+}
+
+// RefreshJob is a run of a view refresh, persisted in the view_refresh_jobs table
+type RefreshJob struct {
+	ID         int64                   `gorm:"primary_key;column:id;type:BIGSERIAL;" json:"-"`
+	JobID      string                  `gorm:"column:job_id;type:TEXT;unique_index;" json:"jobId"`
+	ViewName   string                  `gorm:"column:view_name;type:TEXT;index;" json:"viewName"`
+	Trigger    string                  `gorm:"column:triggered_by;type:TEXT;" json:"trigger"`
+	Status     string                  `gorm:"column:status;type:TEXT;" json:"status"`
+	StartedAt  time.Time               `gorm:"column:started_at;type:TIMESTAMPTZ;" json:"startedAt"`
+	FinishedAt *time.Time              `gorm:"column:finished_at;type:TIMESTAMPTZ;" json:"finishedAt,omitempty"`
+	DurationMs int64                   `gorm:"column:duration_ms;type:INT8;" json:"durationMs"`
+	Statements int                     `gorm:"column:statements;type:INT8;" json:"statements"`
+	Failed     int                     `gorm:"column:failed;type:INT8;" json:"failed"`
+	ErrorsJSON string                  `gorm:"column:errors;type:TEXT;" json:"-"`
+	Errors     []RefreshStatementError `gorm:"-" json:"errors,omitempty"`
+}
+
+// TableName sets the insert table name for this struct type
+func (j *RefreshJob) TableName() string {
+	return "view_refresh_jobs"
+}
+
+// refreshJobTracker makes sure a view has at most one running refresh
+type refreshJobTracker struct {
+	sync.Mutex
+	running map[string]*RefreshJob
+}
+
+// BeginRefreshJob records the start of a refresh of viewName.
+// error - ErrRefreshInProgress when a refresh of viewName is already running
+func BeginRefreshJob(viewName, trigger string) (*RefreshJob, error) {
+	refreshJobs.Lock()
+	if running, ok := refreshJobs.running[viewName]; ok {
+		refreshJobs.Unlock()
+		return nil, fmt.Errorf("%w: job %s started at %s", ErrRefreshInProgress, running.JobID, running.StartedAt.Format(time.RFC3339))
+	}
+
+	job := &RefreshJob{
+		JobID:     uuid.NewV4().String(),
+		ViewName:  viewName,
+		Trigger:   trigger,
+		Status:    RefreshStatusRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	refreshJobs.running[viewName] = job
+	record := *job
+	refreshJobs.Unlock()
+
+	// the insert runs outside the lock so a slow database does not block the readers of running jobs
+	if err := DB.Create(&record).Error; err != nil {
+		log.Printf("unable to record refresh job %s of %s, the error is '%v'", job.JobID, viewName, err)
+	}
+
+	refreshJobs.Lock()
+	job.ID = record.ID
+	refreshJobs.Unlock()
+	return job, nil
+}
+
+// FinishRefreshJob records the end of job, statements is the number of statements run and errs the ones that failed
+func FinishRefreshJob(job *RefreshJob, statements int, errs []RefreshStatementError) {
+	refreshJobs.Lock()
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.DurationMs = finishedAt.Sub(job.StartedAt).Milliseconds()
+	job.Statements = statements
+	job.Failed = len(errs)
+	job.Errors = errs
+	switch {
+	case len(errs) == 0:
+		job.Status = RefreshStatusSucceeded
+	case len(errs) < statements:
+		job.Status = RefreshStatusPartial
+	default:
+		job.Status = RefreshStatusFailed
+	}
+	if len(errs) > 0 {
+		if b, err := json.Marshal(errs); err == nil {
+			job.ErrorsJSON = string(b)
+		}
+	}
+
+	delete(refreshJobs.running, job.ViewName)
+	record := *job
+	refreshJobs.Unlock()
+
+	if err := DB.Save(&record).Error; err != nil {
+		log.Printf("unable to record result of refresh job %s of %s, the error is '%v'", job.JobID, job.ViewName, err)
+	}
+}
+
+// DeferRefreshJob records that job did not run because another instance holds the refresh lock
+func DeferRefreshJob(job *RefreshJob) {
+	refreshJobs.Lock()
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.DurationMs = finishedAt.Sub(job.StartedAt).Milliseconds()
+	job.Status = RefreshStatusDeferred
+
+	delete(refreshJobs.running, job.ViewName)
+	record := *job
+	refreshJobs.Unlock()
+
+	if err := DB.Save(&record).Error; err != nil {
+		log.Printf("unable to record deferral of refresh job %s of %s, the error is '%v'", job.JobID, job.ViewName, err)
+	}
+}
+
+// RunningRefreshJob returns a copy of the running refresh of viewName, or nil
+func RunningRefreshJob(viewName string) *RefreshJob {
+	refreshJobs.Lock()
+	defer refreshJobs.Unlock()
+
+	job, ok := refreshJobs.running[viewName]
+	if !ok {
+		return nil
+	}
+	running := *job
+	return &running
+}
+
+// GetRefreshJobHistory returns the latest runs of viewName, newest first
+func GetRefreshJobHistory(viewName string, limit int) ([]*RefreshJob, error) {
+	if limit <= 0 || limit > MaxRefreshHistory {
+		limit = MaxRefreshHistory
+	}
+
+	var jobs []*RefreshJob
+	if err := DB.Where("view_name = ?", viewName).Order("started_at desc").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		decodeRefreshErrors(job)
+	}
+
+	return jobs, nil
+}
+
+// decodeRefreshErrors fills job.Errors from the errors column, text that is not json is kept as a single error
+func decodeRefreshErrors(job *RefreshJob) {
+	if job.ErrorsJSON == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(job.ErrorsJSON), &job.Errors); err != nil {
+		job.Errors = []RefreshStatementError{{Error: job.ErrorsJSON}}
+	}
+}
+
+// RecoverRefreshJobs marks runs left running by a stopped process as interrupted, call it once at startup.
+// A running refresh holds the advisory lock of its view group, so the runs of a group are only marked once its lock
+// is taken here. Runs of a group locked by a live instance are left running.
+func RecoverRefreshJobs() error {
+	var names []string
+	if err := DB.Model(&RefreshJob{}).Where("status = ?", RefreshStatusRunning).Pluck("distinct view_name", &names).Error; err != nil {
+		return err
+	}
+
+	byGroup := make(map[string][]string)
+	for _, name := range names {
+		group := refreshLockGroup(name)
+		byGroup[group] = append(byGroup[group], name)
+	}
+
+	for group, names := range byGroup {
+		lock, err := tryRefreshLock(group)
+		if err != nil {
+			return err
+		}
+		if lock == nil {
+			continue
+		}
+
+		err = DB.Model(&RefreshJob{}).Where("status = ? AND view_name IN (?)", RefreshStatusRunning, names).
+			Update("status", RefreshStatusInterrupted).Error
+		lock.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
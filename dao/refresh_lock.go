@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// refreshLockClass first key of the advisory locks taken for view refreshes, the second key is the hashed group name
+const refreshLockClass = 7261
+
+// ErrRefreshDeferred error when another instance holds the refresh lock of the view group
+var ErrRefreshDeferred = fmt.Errorf("refresh deferred, another instance is refreshing")
+
+// localRefreshGroups are the view groups whose refresh lock is held by this process, contention on them is a
+// refresh in progress here rather than on another instance
+var localRefreshGroups = struct {
+	sync.Mutex
+	held map[string]bool
+}{held: make(map[string]bool)}
+
+// refreshLock is a session advisory lock held on a dedicated connection for the duration of a refresh,
+// so replicas sharing the database do not refresh the same view group at the same time
+type refreshLock struct {
+	group string
+	conn  *sql.Conn
+}
+
+// tryRefreshLock takes the advisory lock of group without waiting, it returns nil with no error when another
+// instance holds it
+// error - ErrRefreshInProgress when this process holds it, db error
+func tryRefreshLock(group string) (*refreshLock, error) {
+	localRefreshGroups.Lock()
+	if localRefreshGroups.held[group] {
+		localRefreshGroups.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrRefreshInProgress, group)
+	}
+	localRefreshGroups.held[group] = true
+	localRefreshGroups.Unlock()
+
+	lock, err := tryAdvisoryLock(group)
+	if lock == nil {
+		releaseLocalRefreshGroup(group)
+	}
+	return lock, err
+}
+
+func tryAdvisoryLock(group string) (*refreshLock, error) {
+	ctx := context.Background()
+	conn, err := DB.DB().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", refreshLockClass, group).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+
+	return &refreshLock{group: group, conn: conn}, nil
+}
+
+func releaseLocalRefreshGroup(group string) {
+	localRefreshGroups.Lock()
+	defer localRefreshGroups.Unlock()
+	delete(localRefreshGroups.held, group)
+}
+
+// Release unlocks the group and returns the connection to the pool
+func (l *refreshLock) Release() {
+	if _, err := l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, hashtext($2))", refreshLockClass, l.group); err != nil {
+		log.Printf("unable to release refresh lock of %s, the error is '%v'", l.group, err)
+		// discard the connection instead of returning it to the pool, ending the session releases the lock
+		l.conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+	releaseLocalRefreshGroup(l.group)
+}
+
+// refreshLockGroup is the view group whose lock covers a refresh of name, a group or a single view
+func refreshLockGroup(name string) string {
+	if view := ViewByName(name); view != nil {
+		return view.Group
+	}
+	return name
+}
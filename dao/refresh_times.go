@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"sync"
+	"time"
+)
+
+// refreshTimesTTL how long the last refresh times read from view_refresh_jobs are trusted before reading them again,
+// refreshes run by other instances become visible after at most this long
+const refreshTimesTTL = time.Minute
+
+var refreshTimes = struct {
+	sync.Mutex
+	byGroup  map[string]time.Time
+	loadedAt map[string]time.Time
+}{byGroup: make(map[string]time.Time), loadedAt: make(map[string]time.Time)}
+
+func init() {
+	OnRefreshComplete(recordRefreshTime)
+}
+
+// LastViewRefresh returns when a refresh of group, or of one of its views, last finished with at least one
+// statement succeeding. ok is false when the group was never refreshed.
+func LastViewRefresh(group string) (last time.Time, ok bool) {
+	refreshTimes.Lock()
+	stale := time.Since(refreshTimes.loadedAt[group]) > refreshTimesTTL
+	refreshTimes.Unlock()
+
+	// the query runs outside the lock so a slow database does not block the lookups of other groups
+	if stale {
+		names := []string{group}
+		for _, view := range ViewsInGroup(group) {
+			names = append(names, view.Name)
+		}
+
+		var job RefreshJob
+		res := DB.Where("view_name IN (?) AND status IN (?) AND finished_at IS NOT NULL", names, []string{RefreshStatusSucceeded, RefreshStatusPartial}).
+			Order("finished_at desc").
+			First(&job)
+
+		refreshTimes.Lock()
+		if res.Error == nil && job.FinishedAt.After(refreshTimes.byGroup[group]) {
+			refreshTimes.byGroup[group] = *job.FinishedAt
+		}
+		if res.Error == nil || res.RecordNotFound() {
+			refreshTimes.loadedAt[group] = time.Now()
+		}
+		refreshTimes.Unlock()
+	}
+
+	refreshTimes.Lock()
+	defer refreshTimes.Unlock()
+
+	last, ok = refreshTimes.byGroup[group]
+	return last, ok
+}
+
+// recordRefreshTime is the refresh listener keeping LastViewRefresh current for refreshes run by this instance
+func recordRefreshTime(event *RefreshEvent) {
+	if event.Job.FinishedAt == nil || (event.Job.Status != RefreshStatusSucceeded && event.Job.Status != RefreshStatusPartial) {
+		return
+	}
+
+	groups := make(map[string]bool)
+	for _, name := range event.Views {
+		if view := ViewByName(name); view != nil {
+			groups[view.Group] = true
+		}
+	}
+
+	refreshTimes.Lock()
+	defer refreshTimes.Unlock()
+
+	for group := range groups {
+		if event.Job.FinishedAt.After(refreshTimes.byGroup[group]) {
+			refreshTimes.byGroup[group] = *event.Job.FinishedAt
+		}
+	}
+}
@@ -1,56 +1,101 @@
 package dao
 
 import (
-	"log"
-	"os"
+	"errors"
+	"fmt"
 )
 
-type RefreshGlobalStatsViewResponse struct {
-	Message     string `json:"message"`
-	Description string `json:"description"`
-}
-
-var ViewRefreshes = make(map[string]bool)
+var (
+	// ErrUnknownView error when a name is neither a view group nor a registered view
+	ErrUnknownView = fmt.Errorf("unknown view")
+)
 
-func RefreshGlobalStatsView(viewName string) (interface{}, error) {
+// IsRefreshableView reports whether name is a view group or a registered view
+func IsRefreshableView(name string) bool {
+	_, err := ViewsFor(name)
+	return err == nil
+}
 
-	refreshViews, err := os.ReadFile("sql/views/refresh_mv_stats.sql")
-	refreshViewsStr := string(refreshViews)
+// RefreshView refreshes the views of name, a view group or a single view, and waits for the refresh to finish.
+// error - ErrUnknownView, ErrRefreshInProgress or ErrRefreshDeferred, a refresh that ran but failed is reported
+// in the job status
+func RefreshView(name, trigger string) (*RefreshJob, error) {
+	job, views, lock, err := beginViewRefresh(name, trigger)
 	if err != nil {
-		log.Fatalf("Got error when reading refresh_mv_stats.sql, the error is '%v'", err)
-	}
-	if err := DB.Exec(refreshViewsStr); err != nil {
-		panic(err)
+		return job, err
 	}
 
-	refreshGlobalStatsViewResponse := RefreshGlobalStatsViewResponse{
-		Message:     "Success",
-		Description: "Refreshed Global Stats View",
-	}
+	runViewRefresh(job, views, lock)
+	return job, nil
+}
 
-	ViewRefreshes[viewName] = false
-	return refreshGlobalStatsViewResponse, nil
+// StartViewRefresh starts a refresh of the views of name in the background and returns a copy of its job
+// error - ErrUnknownView, ErrRefreshInProgress or ErrRefreshDeferred
+func StartViewRefresh(name, trigger string) (*RefreshJob, error) {
+	job, views, lock, err := beginViewRefresh(name, trigger)
+	if err != nil {
+		return job, err
+	}
 
+	started := *job
+	go runViewRefresh(job, views, lock)
+	return &started, nil
 }
 
-func RefreshGlobalAllTableView(viewName string) (interface{}, error) {
-
-	refreshViews, err := os.ReadFile("sql/views/refresh_all_tables.sql")
-	refreshViewsStr := string(refreshViews)
+// beginViewRefresh takes the advisory lock of the view group and records the job. When another instance holds
+// the lock the job is recorded as deferred and returned with ErrRefreshDeferred, when this process holds it no job
+// is recorded and ErrRefreshInProgress is returned.
+func beginViewRefresh(name, trigger string) (*RefreshJob, []*MaterializedView, *refreshLock, error) {
+	views, err := ViewsFor(name)
 	if err != nil {
-		log.Fatalf("Got error when reading refresh_mv_stats.sql, the error is '%v'", err)
+		return nil, nil, nil, err
 	}
-	if err := DB.Exec(refreshViewsStr); err != nil {
-		panic(err)
+
+	group := refreshLockGroup(name)
+	lock, lockErr := tryRefreshLock(group)
+	if errors.Is(lockErr, ErrRefreshInProgress) {
+		return nil, nil, nil, lockErr
 	}
 
-	refreshGlobalStatsViewResponse := RefreshGlobalStatsViewResponse{
-		Message:     "Success",
-		Description: "Refreshed Global Stats View",
+	job, err := BeginRefreshJob(name, trigger)
+	if err != nil {
+		if lock != nil {
+			lock.Release()
+		}
+		return nil, nil, nil, err
 	}
 
-	// set view refresh to false
-	ViewRefreshes[viewName] = false
-	return refreshGlobalStatsViewResponse, nil
+	if lockErr != nil {
+		FinishRefreshJob(job, 0, []RefreshStatementError{{Statement: "pg_try_advisory_lock", Error: lockErr.Error()}})
+		return job, nil, nil, lockErr
+	}
+	if lock == nil {
+		DeferRefreshJob(job)
+		return job, nil, nil, fmt.Errorf("%w: %s", ErrRefreshDeferred, group)
+	}
 
+	return job, views, lock, nil
+}
+
+// runViewRefresh refreshes views one statement at a time, carrying on past failed statements, then releases lock.
+// It never panics, so a failing refresh cannot take the server down.
+func runViewRefresh(job *RefreshJob, views []*MaterializedView, lock *refreshLock) {
+	var statements int
+	var errs []RefreshStatementError
+	defer func() {
+		if r := recover(); r != nil {
+			errs = append(errs, RefreshStatementError{Error: fmt.Sprintf("refresh aborted: %v", r)})
+		}
+		lock.Release()
+		FinishRefreshJob(job, statements, errs)
+		publishRefreshEvent(job, views)
+	}()
+
+	for i, view := range views {
+		statement := view.RefreshSQL()
+		statements++
+		if err := DB.Exec(statement).Error; err != nil {
+			errs = append(errs, RefreshStatementError{Index: i, Statement: statement, Error: err.Error()})
+		}
+	}
 }
@@ -0,0 +1,104 @@
+package dao
+
+import "strings"
+
+// splitSQLStatements splits a sql script on semicolons that are outside quotes, dollar quoted bodies and comments.
+// Comments are dropped and empty statements are skipped.
+func splitSQLStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(script) {
+				if script[end] == c {
+					// a doubled quote is an escaped quote
+					if end+1 < len(script) && script[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(script) {
+				end = len(script) - 1
+			}
+			current.WriteString(script[i : end+1])
+			i = end
+
+		case c == '$' && dollarTag(script, i) != "":
+			tag := dollarTag(script, i)
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				end = len(script)
+			} else {
+				end += i + 2*len(tag)
+			}
+			current.WriteString(script[i:end])
+			i = end - 1
+
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end
+				current.WriteByte('\n')
+			}
+
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+				current.WriteByte(' ')
+			}
+
+		case c == ';':
+			flush()
+
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarTag returns the opening tag of a dollar quoted string at script[i], $$ or $name$, or "" when there is none.
+// A $ inside an identifier or followed by a digit, a positional parameter, does not open one.
+func dollarTag(script string, i int) string {
+	if i > 0 && isIdentByte(script[i-1]) {
+		return ""
+	}
+
+	for end := i + 1; end < len(script); end++ {
+		c := script[end]
+		switch {
+		case c == '$':
+			return script[i : end+1]
+		case c >= '0' && c <= '9' && end == i+1:
+			return ""
+		case !isIdentByte(c):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"only separators", " ; ;\n", nil},
+		{"single without semicolon", "SELECT 1", []string{"SELECT 1"}},
+		{"several", "SELECT 1;\nSELECT 2 ;", []string{"SELECT 1", "SELECT 2"}},
+		{"semicolon in string", "SELECT 'a;b'; SELECT 2", []string{"SELECT 'a;b'", "SELECT 2"}},
+		{"escaped quote in string", "SELECT 'it''s; here'; SELECT 2", []string{"SELECT 'it''s; here'", "SELECT 2"}},
+		{"semicolon in identifier", `SELECT 1 AS "a;b"; SELECT 2`, []string{`SELECT 1 AS "a;b"`, "SELECT 2"}},
+		{"comment markers in string", "SELECT '-- not a comment', '/* nor this */'", []string{"SELECT '-- not a comment', '/* nor this */'"}},
+		{"line comment", "-- header; with a semicolon\nSELECT 1; -- trailing\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"line comment at the end", "SELECT 1 -- done;", []string{"SELECT 1"}},
+		{"block comment", "SELECT /* a; b */ 1; /* only a comment; */", []string{"SELECT   1"}},
+		{"unterminated block comment", "SELECT 1; /* open", []string{"SELECT 1"}},
+		{"unterminated string", "SELECT 'open; SELECT 2", []string{"SELECT 'open; SELECT 2"}},
+		{"dollar quoted body", "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql", "SELECT f()"}},
+		{"tagged dollar quoted body", "DO $body$ BEGIN PERFORM 1; END $body$; SELECT 2",
+			[]string{"DO $body$ BEGIN PERFORM 1; END $body$", "SELECT 2"}},
+		{"other tag inside dollar quoted body", "DO $a$ SELECT $$;$$; $a$; SELECT 2", []string{"DO $a$ SELECT $$;$$; $a$", "SELECT 2"}},
+		{"unterminated dollar quoted body", "SELECT 1; DO $$ BEGIN; END", []string{"SELECT 1", "DO $$ BEGIN; END"}},
+		{"positional parameter", "SELECT $1; SELECT $2", []string{"SELECT $1", "SELECT $2"}},
+		{"dollar in identifier", "SELECT a$b$; SELECT 2", []string{"SELECT a$b$", "SELECT 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSQLStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSQLStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
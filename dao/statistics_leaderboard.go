@@ -0,0 +1,189 @@
+package dao
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLeaderboardLimit number of entries returned when limit is not set
+	DefaultLeaderboardLimit = 10
+
+	// MaxLeaderboardLimit largest accepted limit, it is also the number of entries cached per window and sort
+	MaxLeaderboardLimit = 100
+)
+
+var (
+	// LeaderboardWindows are the time windows the dashboard views are computed for
+	LeaderboardWindows = []string{"24h", "7d", "30d", "all"}
+
+	// leaderboardSorts maps each sort-by option to its order clause, ties go to the larger and then the first listed
+	leaderboardSorts = map[string]string{
+		"bytes":        "bytes_onboarded desc, deals_succeeded desc",
+		"deals":        "deals_succeeded desc, bytes_onboarded desc",
+		"success_rate": "success_rate desc, deals_succeeded desc",
+	}
+)
+
+func init() {
+	for _, window := range LeaderboardWindows {
+		for sortBy := range leaderboardSorts {
+			query := &LeaderboardQuery{Window: window, SortBy: sortBy, Limit: MaxLeaderboardLimit}
+			RegisterCachedQuery(query.cacheKey("leaderboardSps"), []string{"mv_top_sp_miners"}, func() error {
+				_, err := GetSPLeaderboard(query)
+				return err
+			})
+			RegisterCachedQuery(query.cacheKey("leaderboardNodes"), []string{"mv_top_delta_nodes"}, func() error {
+				_, err := GetNodeLeaderboard(query)
+				return err
+			})
+		}
+	}
+}
+
+// LeaderboardQuery selects a leaderboard page
+type LeaderboardQuery struct {
+	// Window one of LeaderboardWindows
+	Window string
+
+	// SortBy bytes, deals or success_rate
+	SortBy string
+
+	// Limit number of entries, at most MaxLeaderboardLimit
+	Limit int
+}
+
+// ParseLeaderboardQuery reads the limit, window and sort_by query parameters, defaulting to the top 10 by bytes of all time
+// error - ErrBadParams, unknown window or sort, limit not between 1 and MaxLeaderboardLimit
+func ParseLeaderboardQuery(values url.Values) (*LeaderboardQuery, error) {
+	query := &LeaderboardQuery{Window: "all", SortBy: "bytes", Limit: DefaultLeaderboardLimit}
+
+	if window := values.Get("window"); window != "" {
+		if !validLeaderboardWindow(window) {
+			return nil, fmt.Errorf("%w: window must be one of %s", ErrBadParams, strings.Join(LeaderboardWindows, ", "))
+		}
+		query.Window = window
+	}
+
+	if sortBy := values.Get("sort_by"); sortBy != "" {
+		if _, ok := leaderboardSorts[sortBy]; !ok {
+			return nil, fmt.Errorf("%w: sort_by must be one of bytes, deals, success_rate", ErrBadParams)
+		}
+		query.SortBy = sortBy
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLeaderboardLimit {
+			return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrBadParams, MaxLeaderboardLimit)
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
+
+// cacheKey the full top MaxLeaderboardLimit of a window and sort is cached once, smaller limits are cut from it
+func (q *LeaderboardQuery) cacheKey(prefix string) string {
+	return prefix + ":" + q.Window + ":" + q.SortBy
+}
+
+func validLeaderboardWindow(window string) bool {
+	for _, w := range LeaderboardWindows {
+		if w == window {
+			return true
+		}
+	}
+	return false
+}
+
+// SPRanking is a storage provider row of mv_top_sp_miners
+type SPRanking struct {
+	Rank           int       `gorm:"-" json:"rank"`
+	Miner          string    `gorm:"column:miner" json:"miner"`
+	DealsAttempted int64     `gorm:"column:deals_attempted" json:"deals_attempted"`
+	DealsSucceeded int64     `gorm:"column:deals_succeeded" json:"deals_succeeded"`
+	BytesOnboarded int64     `gorm:"column:bytes_onboarded" json:"bytes_onboarded"`
+	SuccessRate    float64   `gorm:"column:success_rate" json:"success_rate"`
+	FirstSeen      time.Time `gorm:"column:first_seen" json:"first_seen"`
+	LastSeen       time.Time `gorm:"column:last_seen" json:"last_seen"`
+}
+
+// NodeRanking is a delta node row of mv_top_delta_nodes
+type NodeRanking struct {
+	Rank           int       `gorm:"-" json:"rank"`
+	DeltaNodeUUID  string    `gorm:"column:delta_node_uuid" json:"delta_node_uuid"`
+	OsDetails      string    `gorm:"column:os_details" json:"os_details"`
+	PublicIP       string    `gorm:"column:public_ip" json:"public_ip"`
+	DealsAttempted int64     `gorm:"column:deals_attempted" json:"deals_attempted"`
+	DealsSucceeded int64     `gorm:"column:deals_succeeded" json:"deals_succeeded"`
+	BytesOnboarded int64     `gorm:"column:bytes_onboarded" json:"bytes_onboarded"`
+	SuccessRate    float64   `gorm:"column:success_rate" json:"success_rate"`
+	FirstSeen      time.Time `gorm:"column:first_seen" json:"first_seen"`
+	LastSeen       time.Time `gorm:"column:last_seen" json:"last_seen"`
+}
+
+// GetSPLeaderboard returns the top storage providers of the window, ranked by the sort of query
+// error - db error
+func GetSPLeaderboard(query *LeaderboardQuery) ([]*SPRanking, error) {
+	var rankings []*SPRanking
+	err := cacheFetch(query.cacheKey("leaderboardSps"), &rankings, func() (interface{}, error) {
+		var rankings []*SPRanking
+		err := DB.Table("mv_top_sp_miners").
+			Where("time_window = ?", query.Window).
+			Order(leaderboardSorts[query.SortBy] + ", miner").
+			Limit(MaxLeaderboardLimit).
+			Find(&rankings).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for i, ranking := range rankings {
+			ranking.Rank = i + 1
+		}
+		return rankings, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rankings) > query.Limit {
+		rankings = rankings[:query.Limit]
+	}
+	return rankings, nil
+}
+
+// GetNodeLeaderboard returns the top delta nodes of the window, ranked by the sort of query
+// error - db error
+func GetNodeLeaderboard(query *LeaderboardQuery) ([]*NodeRanking, error) {
+	var rankings []*NodeRanking
+	err := cacheFetch(query.cacheKey("leaderboardNodes"), &rankings, func() (interface{}, error) {
+		var rankings []*NodeRanking
+		err := DB.Table("mv_top_delta_nodes").
+			Select("time_window, delta_node_uuid, coalesce(os_details, '') as os_details, coalesce(public_ip, '') as public_ip, "+
+				"deals_attempted, deals_succeeded, bytes_onboarded, success_rate, first_seen, last_seen").
+			Where("time_window = ?", query.Window).
+			Order(leaderboardSorts[query.SortBy] + ", delta_node_uuid").
+			Limit(MaxLeaderboardLimit).
+			Find(&rankings).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for i, ranking := range rankings {
+			ranking.Rank = i + 1
+		}
+		return rankings, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rankings) > query.Limit {
+		rankings = rankings[:query.Limit]
+	}
+	return rankings, nil
+}
@@ -0,0 +1,312 @@
+package dao
+
+import (
+	"sort"
+	"time"
+
+	"github.com/guregu/null"
+)
+
+const (
+	// NodeStatusOnline the node wrote a log within NodeStaleAfter
+	NodeStatusOnline = "online"
+
+	// NodeStatusStale the node has not written a log for longer than NodeStaleAfter
+	NodeStatusStale = "stale"
+
+	// maxNodeStartupHistory number of startups returned in a node profile
+	maxNodeStartupHistory = 50
+)
+
+// NodeStaleAfter time without any log after which a delta node is reported stale
+var NodeStaleAfter = time.Hour
+
+// DeltaNode is the inventory entry of a delta node, joined from its startup, instance meta, geo location and content logs
+type DeltaNode struct {
+	DeltaNodeUUID string     `json:"delta_node_uuid"`
+	Status        string     `json:"status"`
+	FirstSeen     *time.Time `json:"first_seen"`
+	LastSeen      *time.Time `json:"last_seen"`
+	OsDetails     string     `json:"os_details"`
+	IPAddress     string     `json:"ip_address"`
+	Startups      int64      `json:"startups"`
+
+	DealsAttempted        int64 `json:"deals_attempted"`
+	DealsSucceeded        int64 `json:"deals_succeeded"`
+	BytesOnboarded        int64 `json:"bytes_onboarded"`
+	DealsAttemptedPast24h int64 `json:"deals_attempted_past_24h"`
+	DealsSucceededPast24h int64 `json:"deals_succeeded_past_24h"`
+	BytesOnboardedPast24h int64 `json:"bytes_onboarded_past_24h"`
+
+	Instance    *NodeInstanceMeta `json:"instance"`
+	GeoLocation *NodeGeoLocation  `json:"geo_location"`
+}
+
+// DeltaNodeProfile is a delta node with its latest startups
+type DeltaNodeProfile struct {
+	*DeltaNode
+	StartupHistory []*NodeStartup `json:"startup_history"`
+}
+
+// NodeInstanceMeta is the latest instance_meta_logs snapshot of a node
+type NodeInstanceMeta struct {
+	DeltaNodeUUID                    string     `gorm:"column:delta_node_uuid" json:"-"`
+	InstanceUUID                     string     `gorm:"column:instance_uuid" json:"instance_uuid"`
+	InstanceHostName                 string     `gorm:"column:instance_host_name" json:"instance_host_name"`
+	InstanceNodeName                 string     `gorm:"column:instance_node_name" json:"instance_node_name"`
+	OsDetails                        string     `gorm:"column:os_details" json:"os_details"`
+	PublicIP                         string     `gorm:"column:public_ip" json:"public_ip"`
+	MemoryLimit                      null.Int   `gorm:"column:memory_limit" json:"memory_limit"`
+	CPULimit                         null.Int   `gorm:"column:cpu_limit" json:"cpu_limit"`
+	StorageLimit                     null.Int   `gorm:"column:storage_limit" json:"storage_limit"`
+	NumberOfCpus                     null.Int   `gorm:"column:number_of_cpus" json:"number_of_cpus"`
+	StorageInBytes                   null.Int   `gorm:"column:storage_in_bytes" json:"storage_in_bytes"`
+	SystemMemory                     null.Int   `gorm:"column:system_memory" json:"system_memory"`
+	DisableRequest                   null.Bool  `gorm:"column:disable_request" json:"disable_request"`
+	DisableCommitmentPieceGeneration null.Bool  `gorm:"column:disable_commitment_piece_generation" json:"disable_commitment_piece_generation"`
+	DisableStorageDeal               null.Bool  `gorm:"column:disable_storage_deal" json:"disable_storage_deal"`
+	DisableOnlineDeals               null.Bool  `gorm:"column:disable_online_deals" json:"disable_online_deals"`
+	DisableOfflineDeals              null.Bool  `gorm:"column:disable_offline_deals" json:"disable_offline_deals"`
+	InstanceStart                    *time.Time `gorm:"column:instance_start" json:"instance_start"`
+	ReportedAt                       *time.Time `gorm:"column:created_at" json:"reported_at"`
+}
+
+// NodeGeoLocation is the latest delta_node_geo_locations row of a node's ip
+type NodeGeoLocation struct {
+	IP      string     `gorm:"column:ip" json:"ip"`
+	Country string     `gorm:"column:country" json:"country"`
+	City    string     `gorm:"column:city" json:"city"`
+	Region  string     `gorm:"column:region" json:"region"`
+	Zip     string     `gorm:"column:zip" json:"zip"`
+	Lat     null.Float `gorm:"column:lat" json:"lat"`
+	Lon     null.Float `gorm:"column:lon" json:"lon"`
+}
+
+// NodeStartup is a delta_startup_logs row of a node
+type NodeStartup struct {
+	OsDetails string     `gorm:"column:os_details" json:"os_details"`
+	IPAddress string     `gorm:"column:ip_address" json:"ip_address"`
+	StartedAt *time.Time `gorm:"column:created_at" json:"started_at"`
+}
+
+// nodeStartupSummary is the per node aggregate of delta_startup_logs
+type nodeStartupSummary struct {
+	DeltaNodeUUID string     `gorm:"column:delta_node_uuid"`
+	Startups      int64      `gorm:"column:startups"`
+	FirstSeen     *time.Time `gorm:"column:first_seen"`
+	LastSeen      *time.Time `gorm:"column:last_seen"`
+	OsDetails     string     `gorm:"column:os_details"`
+	IPAddress     string     `gorm:"column:ip_address"`
+}
+
+// nodeDealSummary is the per node aggregate of content_logs, a deal is a content handled by the node
+type nodeDealSummary struct {
+	DeltaNodeUUID         string     `gorm:"column:delta_node_uuid"`
+	DealsAttempted        int64      `gorm:"column:deals_attempted"`
+	DealsSucceeded        int64      `gorm:"column:deals_succeeded"`
+	BytesOnboarded        int64      `gorm:"column:bytes_onboarded"`
+	DealsAttemptedPast24h int64      `gorm:"column:deals_attempted_past_24h"`
+	DealsSucceededPast24h int64      `gorm:"column:deals_succeeded_past_24h"`
+	BytesOnboardedPast24h int64      `gorm:"column:bytes_onboarded_past_24h"`
+	FirstSeen             *time.Time `gorm:"column:first_seen"`
+	LastSeen              *time.Time `gorm:"column:last_seen"`
+}
+
+// GetDeltaNodes returns the inventory of every delta node, sorted by uuid
+// error - db error
+func GetDeltaNodes() ([]*DeltaNode, error) {
+	var nodes []*DeltaNode
+	err := cacheFetch("deltaNodes", &nodes, func() (interface{}, error) {
+		return loadDeltaNodes("")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		node.Status = nodeStatus(node.LastSeen)
+	}
+	return nodes, nil
+}
+
+// GetDeltaNodeProfile returns the inventory entry of a delta node with its latest startups
+// error - ErrNotFound when no log mentions the node, db error
+func GetDeltaNodeProfile(deltaNodeUUID string) (*DeltaNodeProfile, error) {
+	var profile DeltaNodeProfile
+	err := cacheFetch("deltaNode:"+deltaNodeUUID, &profile, func() (interface{}, error) {
+		nodes, err := loadDeltaNodes(deltaNodeUUID)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			return nil, ErrNotFound
+		}
+
+		profile := &DeltaNodeProfile{DeltaNode: nodes[0], StartupHistory: []*NodeStartup{}}
+		err = DB.Table("delta_startup_logs").
+			Select("coalesce(os_details, '') as os_details, coalesce(ip_address, '') as ip_address, created_at").
+			Where("delta_node_uuid = ?", deltaNodeUUID).
+			Order("created_at desc").
+			Limit(maxNodeStartupHistory).
+			Find(&profile.StartupHistory).Error
+		if err != nil {
+			return nil, err
+		}
+		return profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Status = nodeStatus(profile.LastSeen)
+	return &profile, nil
+}
+
+// loadDeltaNodes joins the logs of the node deltaNodeUUID, or of every node when it is empty
+func loadDeltaNodes(deltaNodeUUID string) ([]*DeltaNode, error) {
+	where, args := "delta_node_uuid <> ''", []interface{}{}
+	if deltaNodeUUID != "" {
+		where, args = "delta_node_uuid = ?", []interface{}{deltaNodeUUID}
+	}
+
+	var startups []*nodeStartupSummary
+	err := DB.Table("delta_startup_logs").
+		Select("delta_node_uuid, count(*) as startups, min(created_at) as first_seen, max(created_at) as last_seen, "+
+			"coalesce((array_agg(os_details order by created_at desc))[1], '') as os_details, "+
+			"coalesce((array_agg(ip_address order by created_at desc))[1], '') as ip_address").
+		Where(where, args...).
+		Group("delta_node_uuid").
+		Find(&startups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var metas []*NodeInstanceMeta
+	err = DB.Raw(`select distinct on (delta_node_uuid) delta_node_uuid, coalesce(instance_uuid, '') as instance_uuid,
+			coalesce(instance_host_name, '') as instance_host_name, coalesce(instance_node_name, '') as instance_node_name,
+			coalesce(os_details, '') as os_details, coalesce(public_ip, '') as public_ip, memory_limit, cpu_limit, storage_limit,
+			number_of_cpus, storage_in_bytes, system_memory, disable_request, disable_commitment_piece_generation,
+			disable_storage_deal, disable_online_deals, disable_offline_deals, instance_start, created_at
+		from instance_meta_logs
+		where `+where+`
+		order by delta_node_uuid, created_at desc`, args...).
+		Scan(&metas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var deals []*nodeDealSummary
+	err = DB.Raw(`select delta_node_uuid, count(*) as deals_attempted, count(*) filter (where succeeded) as deals_succeeded,
+			coalesce(sum(size) filter (where succeeded), 0)::int8 as bytes_onboarded,
+			count(*) filter (where last_seen > now() - interval '24 hours') as deals_attempted_past_24h,
+			count(*) filter (where succeeded and last_seen > now() - interval '24 hours') as deals_succeeded_past_24h,
+			coalesce(sum(size) filter (where succeeded and last_seen > now() - interval '24 hours'), 0)::int8 as bytes_onboarded_past_24h,
+			min(first_seen) as first_seen, max(last_seen) as last_seen
+		from (
+			select delta_node_uuid, max(size) as size, bool_or(status in (?)) as succeeded,
+				min(created_at) as first_seen, max(created_at) as last_seen
+			from content_logs
+			where `+where+`
+			group by delta_node_uuid, system_content_id
+		) deals
+		group by delta_node_uuid`, append([]interface{}{dealSucceededStatuses}, args...)...).
+		Scan(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byUUID := make(map[string]*DeltaNode)
+	node := func(uuid string) *DeltaNode {
+		if n, ok := byUUID[uuid]; ok {
+			return n
+		}
+		n := &DeltaNode{DeltaNodeUUID: uuid}
+		byUUID[uuid] = n
+		return n
+	}
+
+	for _, startup := range startups {
+		n := node(startup.DeltaNodeUUID)
+		n.Startups = startup.Startups
+		n.OsDetails = startup.OsDetails
+		n.IPAddress = startup.IPAddress
+		n.seen(startup.FirstSeen, startup.LastSeen)
+	}
+
+	for _, meta := range metas {
+		n := node(meta.DeltaNodeUUID)
+		n.Instance = meta
+		if meta.OsDetails != "" {
+			n.OsDetails = meta.OsDetails
+		}
+		if meta.PublicIP != "" {
+			n.IPAddress = meta.PublicIP
+		}
+		n.seen(meta.ReportedAt, meta.ReportedAt)
+	}
+
+	for _, deal := range deals {
+		n := node(deal.DeltaNodeUUID)
+		n.DealsAttempted = deal.DealsAttempted
+		n.DealsSucceeded = deal.DealsSucceeded
+		n.BytesOnboarded = deal.BytesOnboarded
+		n.DealsAttemptedPast24h = deal.DealsAttemptedPast24h
+		n.DealsSucceededPast24h = deal.DealsSucceededPast24h
+		n.BytesOnboardedPast24h = deal.BytesOnboardedPast24h
+		n.seen(deal.FirstSeen, deal.LastSeen)
+	}
+
+	// only the ips the nodes report are looked up, the geo location table holds every ip ever logged
+	ips := make([]string, 0, len(byUUID))
+	for _, n := range byUUID {
+		if n.IPAddress != "" {
+			ips = append(ips, n.IPAddress)
+		}
+	}
+
+	var geos []*NodeGeoLocation
+	if len(ips) > 0 {
+		err = DB.Raw(`select distinct on (ip) ip, coalesce(country, '') as country, coalesce(city, '') as city,
+				coalesce(region, '') as region, coalesce(zip, '') as zip, lat, lon
+			from delta_node_geo_locations
+			where ip in (?)
+			order by ip, created_at desc`, ips).
+			Scan(&geos).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	geoByIP := make(map[string]*NodeGeoLocation, len(geos))
+	for _, geo := range geos {
+		geoByIP[geo.IP] = geo
+	}
+
+	nodes := make([]*DeltaNode, 0, len(byUUID))
+	for _, n := range byUUID {
+		n.GeoLocation = geoByIP[n.IPAddress]
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].DeltaNodeUUID < nodes[j].DeltaNodeUUID
+	})
+	return nodes, nil
+}
+
+// seen widens the first and last seen times of the node to include first and last
+func (n *DeltaNode) seen(first, last *time.Time) {
+	if first != nil && (n.FirstSeen == nil || first.Before(*n.FirstSeen)) {
+		n.FirstSeen = first
+	}
+	if last != nil && (n.LastSeen == nil || last.After(*n.LastSeen)) {
+		n.LastSeen = last
+	}
+}
+
+// nodeStatus is computed when the node is returned rather than cached, a cached node still turns stale on time
+func nodeStatus(lastSeen *time.Time) string {
+	if lastSeen == nil || time.Since(*lastSeen) > NodeStaleAfter {
+		return NodeStatusStale
+	}
+	return NodeStatusOnline
+}
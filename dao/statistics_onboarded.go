@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"fmt"
+)
+
+const (
+	// OnboardedDealsBySP filters onboarded deals on the storage provider
+	OnboardedDealsBySP = "miner"
+
+	// OnboardedDealsByDeltaNode filters onboarded deals on the delta node uuid
+	OnboardedDealsByDeltaNode = "delta_node_uuid"
+
+	// OnboardedDealsByKey filters onboarded deals on the api key that requested them
+	OnboardedDealsByKey = "requesting_api_key"
+
+	// MaxOnboardedPageSize largest page of onboarded deals
+	MaxOnboardedPageSize = 100
+)
+
+// OnboardedDeal is a deal of mv_onboarded_deals_by_sp_uuid_key, the requesting api key is never returned
+type OnboardedDeal struct {
+	SystemContentID int64  `gorm:"column:system_content_id" json:"system_content_id"`
+	DeltaNodeUUID   string `gorm:"column:delta_node_uuid" json:"delta_node_uuid"`
+	Miner           string `gorm:"column:miner" json:"miner"`
+	DealUUID        string `gorm:"column:deal_uuid" json:"deal_uuid"`
+	DealID          int64  `gorm:"column:deal_id" json:"deal_id"`
+	PaddedPieceSize int64  `gorm:"column:size" json:"padded_piece_size"`
+}
+
+// OnboardedDeals is a page of the onboarded deals matching a lookup, with the totals of all of them
+type OnboardedDeals struct {
+	DealCount   int64            `json:"deal_count"`
+	PaddedBytes int64            `json:"padded_bytes"`
+	Page        int64            `json:"page"`
+	PageSize    int64            `json:"pageSize"`
+	Deals       []*OnboardedDeal `json:"deals"`
+}
+
+// GetOnboardedDeals returns a page of the onboarded deals whose dimension column, one of the OnboardedDealsBy
+// constants, equals value. Pages are numbered like the table endpoints, from 1 with 0 also the first page.
+// error - ErrBadParams for an empty value, unknown dimension or page out of range, db error
+func GetOnboardedDeals(dimension, value string, page, pageSize int64) (*OnboardedDeals, error) {
+	switch dimension {
+	case OnboardedDealsBySP, OnboardedDealsByDeltaNode, OnboardedDealsByKey:
+	default:
+		return nil, fmt.Errorf("%w: unknown onboarded deals dimension %s", ErrBadParams, dimension)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%w: %s is required", ErrBadParams, dimension)
+	}
+	if page < 0 || pageSize < 1 || pageSize > MaxOnboardedPageSize {
+		return nil, fmt.Errorf("%w: page must be 0 or more and pagesize between 1 and %d", ErrBadParams, MaxOnboardedPageSize)
+	}
+
+	where := quoteColumn(dimension) + " = ?"
+	result := &OnboardedDeals{Page: page, PageSize: pageSize, Deals: []*OnboardedDeal{}}
+
+	row := DB.Table("mv_onboarded_deals_by_sp_uuid_key").
+		Select("count(*), coalesce(sum(size), 0)::int8").
+		Where(where, value).
+		Row()
+	if err := row.Scan(&result.DealCount, &result.PaddedBytes); err != nil {
+		return nil, err
+	}
+
+	err := DB.Table("mv_onboarded_deals_by_sp_uuid_key").
+		Select("coalesce(system_content_id, 0) as system_content_id, coalesce(delta_node_uuid, '') as delta_node_uuid, "+
+			"coalesce(miner, '') as miner, coalesce(deal_uuid, '') as deal_uuid, coalesce(deal_id, 0) as deal_id, coalesce(size, 0) as size").
+		Where(where, value).
+		Order("system_content_id desc, deal_uuid, deal_id").
+		Offset(pageOffset(page, pageSize)).
+		Limit(pageSize).
+		Find(&result.Deals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package dao
+
+import (
+	"time"
+)
+
+var (
+	// dealSucceededStatuses content_logs statuses of a deal that was proposed and accepted
+	dealSucceededStatuses = []string{"deal-proposal-sent", "transfer-started", "transfer-finished"}
+
+	// e2eSucceededStatuses statuses of a succeeded e2e deal, its data is transferred after the proposal
+	e2eSucceededStatuses = []string{"transfer-started", "transfer-finished"}
+
+	// importSucceededStatuses status of a succeeded import deal, its data is never transferred
+	importSucceededStatuses = []string{"deal-proposal-sent"}
+
+	// dealFailedStatuses content_logs statuses of a deal that failed
+	dealFailedStatuses = []string{"transfer-failed", "deal-proposal-failed", "piece-computing-failed", "failed-to-process"}
+)
+
+// SPProfile is what the logs record about a storage provider. A deal is a content a delta node proposed to it,
+// counted once per content and delta node. Failed deals are the ones that failed and never succeeded on a retry.
+type SPProfile struct {
+	Miner          string `json:"miner"`
+	DealsAttempted int64  `json:"deals_attempted"`
+	DealsSucceeded int64  `json:"deals_succeeded"`
+	DealsFailed    int64  `json:"deals_failed"`
+
+	// BytesOnboarded padded piece size of the succeeded deals
+	BytesOnboarded           int64 `json:"bytes_onboarded"`
+	VerifiedDeals            int64 `json:"verified_deals"`
+	VerifiedBytesOnboarded   int64 `json:"verified_bytes_onboarded"`
+	UnverifiedDeals          int64 `json:"unverified_deals"`
+	UnverifiedBytesOnboarded int64 `json:"unverified_bytes_onboarded"`
+	SlashedDeals             int64 `json:"slashed_deals"`
+
+	FirstSeen     *time.Time        `json:"first_seen"`
+	LastSeen      *time.Time        `json:"last_seen"`
+	MinerVersions []*SPMinerVersion `json:"miner_versions"`
+	DeltaNodes    []*SPDeltaNode    `json:"delta_nodes"`
+}
+
+// SPMinerVersion is a miner version reported in content_deal_logs
+type SPMinerVersion struct {
+	Version   string     `gorm:"column:miner_version" json:"version"`
+	Deals     int64      `gorm:"column:deals" json:"deals"`
+	FirstSeen *time.Time `gorm:"column:first_seen" json:"first_seen"`
+	LastSeen  *time.Time `gorm:"column:last_seen" json:"last_seen"`
+}
+
+// SPDeltaNode is a delta node that proposed deals to the storage provider
+type SPDeltaNode struct {
+	DeltaNodeUUID string     `gorm:"column:delta_node_uuid" json:"delta_node_uuid"`
+	Deals         int64      `gorm:"column:deals" json:"deals"`
+	LastSeen      *time.Time `gorm:"column:last_seen" json:"last_seen"`
+}
+
+// GetSPProfile builds the profile of miner from the log tables
+// error - ErrNotFound when no log mentions miner, db error
+func GetSPProfile(miner string) (*SPProfile, error) {
+	var profile SPProfile
+	err := cacheFetch("spProfile:"+miner, &profile, func() (interface{}, error) {
+		profile := &SPProfile{Miner: miner, MinerVersions: []*SPMinerVersion{}, DeltaNodes: []*SPDeltaNode{}}
+
+		row := DB.Raw(`select count(*), count(*) filter (where succeeded), count(*) filter (where failed and not succeeded),
+				min(first_seen), max(last_seen)
+			from (
+				select coalesce(bool_or(cl.status in (?)), false) as succeeded,
+					coalesce(bool_or(cl.status in (?)), false) or bool_or(coalesce(cd.failed, false)) as failed,
+					min(cml.created_at) as first_seen, max(cml.created_at) as last_seen
+				from content_miner_logs cml
+				left join content_logs cl on cl.system_content_id = cml.content and cl.delta_node_uuid = cml.delta_node_uuid
+				left join content_deal_logs cd on cd.content = cml.content and cd.miner = cml.miner and cd.delta_node_uuid = cml.delta_node_uuid
+				where cml.miner = ?
+				group by cml.content, cml.delta_node_uuid
+			) deals`, dealSucceededStatuses, dealFailedStatuses, miner).Row()
+		if err := row.Scan(&profile.DealsAttempted, &profile.DealsSucceeded, &profile.DealsFailed, &profile.FirstSeen, &profile.LastSeen); err != nil {
+			return nil, err
+		}
+		if profile.DealsAttempted == 0 {
+			return nil, ErrNotFound
+		}
+
+		row = DB.Raw(`select coalesce(sum(size), 0)::int8, count(*) filter (where verified), coalesce(sum(size) filter (where verified), 0)::int8,
+				count(*) filter (where not verified), coalesce(sum(size) filter (where not verified), 0)::int8,
+				(select count(*) from (select distinct content, delta_node_uuid from content_deal_logs where miner = ? and slashed) s)
+			from (
+				select p.padded_piece_size as size, bool_or(coalesce(cd.verified, false)) as verified
+				from content_deal_logs cd
+				join content_logs c on c.system_content_id = cd.content and c.delta_node_uuid = cd.delta_node_uuid
+				join piece_commitment_logs p on c.piece_commitment_id = p.system_content_piece_commitment_id
+				where cd.miner = ? and c.status in (?)
+				group by cd.content, cd.delta_node_uuid, p.padded_piece_size
+			) onboarded`, miner, miner, dealSucceededStatuses).Row()
+		if err := row.Scan(&profile.BytesOnboarded, &profile.VerifiedDeals, &profile.VerifiedBytesOnboarded,
+			&profile.UnverifiedDeals, &profile.UnverifiedBytesOnboarded, &profile.SlashedDeals); err != nil {
+			return nil, err
+		}
+
+		err := DB.Table("content_deal_logs").
+			Select("miner_version, count(distinct content) as deals, min(created_at) as first_seen, max(created_at) as last_seen").
+			Where("miner = ? AND miner_version <> ''", miner).
+			Group("miner_version").
+			Order("last_seen desc").
+			Find(&profile.MinerVersions).Error
+		if err != nil {
+			return nil, err
+		}
+
+		err = DB.Table("content_miner_logs").
+			Select("delta_node_uuid, count(distinct content) as deals, max(created_at) as last_seen").
+			Where("miner = ? AND delta_node_uuid <> ''", miner).
+			Group("delta_node_uuid").
+			Order("deals desc, delta_node_uuid").
+			Find(&profile.DeltaNodes).Error
+		if err != nil {
+			return nil, err
+		}
+
+		return profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
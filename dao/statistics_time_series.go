@@ -1,12 +1,365 @@
 package dao
 
-// function to get all totals info
-func GetDealsAttemptedInRange(from string, to string) (interface{}, error) {
-	var dealsAttempatedInRange int64
-	row := DB.Raw("select sum(cnt) as total_rows from (select count(*) as cnt from content_logs where created_at >= ? and created_at <= ? group by content_id) as t", from, to).Row()
-	err := row.Scan(&dealsAttempatedInRange)
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTimeSeriesSpan range of a time series when from is not set
+	defaultTimeSeriesSpan = 30 * 24 * time.Hour
+
+	// MaxTimeSeriesPoints largest number of buckets a time series may have
+	MaxTimeSeriesPoints = 1000
+)
+
+var (
+	// TimeSeriesIntervals are the accepted bucket sizes, each one is a date_trunc field
+	TimeSeriesIntervals = []string{"hour", "day", "week", "month"}
+
+	// timeSeriesIntervalLength shortest duration of a bucket, a month is at least 28 days. Dividing the range by it
+	// gives the largest number of buckets the range can hold, which is what MaxTimeSeriesPoints is checked against.
+	timeSeriesIntervalLength = map[string]time.Duration{
+		"hour":  time.Hour,
+		"day":   24 * time.Hour,
+		"week":  7 * 24 * time.Hour,
+		"month": 28 * 24 * time.Hour,
+	}
+)
+
+// TimeSeriesMetric is a metric that can be bucketed over time.
+// Its rows come from From, a table or subquery aliased t with a created_at column, and are summed up by Value.
+// Args are bound to the placeholders of From.
+type TimeSeriesMetric struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	From        string        `json:"-"`
+	Args        []interface{} `json:"-"`
+	Value       string        `json:"-"`
+}
+
+// TimeSeriesMetrics is the registry of time series, named like the global totals they break down over time.
+// Sizes count a content or piece once, in the bucket of its first log.
+var TimeSeriesMetrics = []*TimeSeriesMetric{
+	{
+		Name:        "deals_attempted",
+		Description: "content log entries",
+		From:        "content_logs t",
+		Value:       "count(*)",
+	},
+	{
+		Name:        "deals_attempted_size",
+		Description: "size of the contents logged",
+		From:        "(select c.size, min(c.created_at) as created_at from content_logs c group by c.size, c.system_content_id) t",
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "e2e_deals_attempted",
+		Description: "content log entries of e2e deals",
+		From:        "(select * from content_logs c where c.connection_mode = 'e2e') t",
+		Value:       "count(*)",
+	},
+	{
+		Name:        "e2e_deals_attempted_size",
+		Description: "size of the contents logged for e2e deals",
+		From:        "(select c.size, min(c.created_at) as created_at from content_logs c where c.connection_mode = 'e2e' group by c.size, c.system_content_id) t",
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "import_deals_attempted",
+		Description: "content log entries of import deals",
+		From:        "(select * from content_logs c where c.connection_mode = 'import') t",
+		Value:       "count(*)",
+	},
+	{
+		Name:        "import_deals_attempted_size",
+		Description: "size of the contents logged for import deals",
+		From:        "(select c.size, min(c.created_at) as created_at from content_logs c where c.connection_mode = 'import' group by c.size, c.system_content_id) t",
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "deals_succeeded",
+		Description: "content log entries of proposed or transferring deals",
+		From:        "(select * from content_logs c where c.status in (?)) t",
+		Args:        []interface{}{dealSucceededStatuses},
+		Value:       "count(*)",
+	},
+	{
+		Name:        "deals_succeeded_size",
+		Description: "padded piece size of the succeeded deals",
+		From:        succeededSizeFrom(""),
+		Args:        []interface{}{dealSucceededStatuses},
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "e2e_succeeded",
+		Description: "content log entries of transferring e2e deals",
+		From:        "(select * from content_logs c where c.connection_mode = 'e2e' and c.status in (?)) t",
+		Args:        []interface{}{e2eSucceededStatuses},
+		Value:       "count(*)",
+	},
+	{
+		Name:        "e2e_succeeded_size",
+		Description: "padded piece size of the succeeded e2e deals",
+		From:        succeededSizeFrom("e2e"),
+		Args:        []interface{}{e2eSucceededStatuses},
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "import_succeeded",
+		Description: "content log entries of proposed import deals",
+		From:        "(select * from content_logs c where c.connection_mode = 'import' and c.status in (?)) t",
+		Args:        []interface{}{importSucceededStatuses},
+		Value:       "count(*)",
+	},
+	{
+		Name:        "import_succeeded_size",
+		Description: "padded piece size of the succeeded import deals",
+		From:        succeededSizeFrom("import"),
+		Args:        []interface{}{importSucceededStatuses},
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "piece_commitments_compute_attempted",
+		Description: "piece commitment log entries",
+		From:        "(select * from piece_commitment_logs p where p.piece is not null) t",
+		Value:       "count(*)",
+	},
+	{
+		Name:        "piece_commitments_compute_attempted_size",
+		Description: "size of the pieces logged",
+		From:        "(select p.size, min(p.created_at) as created_at from piece_commitment_logs p where p.piece is not null group by p.size, p.piece) t",
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "piece_commitments_compute_succeeded",
+		Description: "piece commitment log entries of committed pieces",
+		From:        "(select * from piece_commitment_logs p where p.piece is not null and p.status = 'committed') t",
+		Value:       "count(*)",
+	},
+	{
+		Name:        "piece_commitments_compute_succeeded_size",
+		Description: "size of the committed pieces",
+		From:        "(select p.size, min(p.created_at) as created_at from piece_commitment_logs p where p.piece is not null and p.status = 'committed' group by p.size, p.piece) t",
+		Value:       "sum(t.size)",
+	},
+	{
+		Name:        "number_of_sps_worked_with",
+		Description: "storage providers that were proposed a deal",
+		From:        "content_miner_logs t",
+		Value:       "count(distinct t.miner)",
+	},
+	{
+		Name:        "number_of_unique_delta_nodes",
+		Description: "delta nodes that started",
+		From:        "delta_startup_logs t",
+		Value:       "count(distinct t.delta_node_uuid)",
+	},
+	{
+		Name:        "in_progress_deals",
+		Description: "content log entries of deals neither failed nor proposed yet",
+		From:        inProgressFrom(""),
+		Args:        []interface{}{dealFailedStatuses, dealSucceededStatuses},
+		Value:       "count(*)",
+	},
+	{
+		Name:        "in_progress_e2e_deals",
+		Description: "content log entries of e2e deals neither failed nor proposed yet",
+		From:        inProgressFrom("e2e"),
+		Args:        []interface{}{dealFailedStatuses, dealSucceededStatuses},
+		Value:       "count(*)",
+	},
+	{
+		Name:        "in_progress_import_deals",
+		Description: "content log entries of import deals neither failed nor proposed yet",
+		From:        inProgressFrom("import"),
+		Args:        []interface{}{dealFailedStatuses, dealSucceededStatuses},
+		Value:       "count(*)",
+	},
+}
+
+// succeededSizeFrom selects the contents whose status is bound to its placeholder, of connectionMode when it is set
+func succeededSizeFrom(connectionMode string) string {
+	return "(select p.padded_piece_size as size, min(c.created_at) as created_at from content_logs c " +
+		"join piece_commitment_logs p on c.piece_commitment_id = p.system_content_piece_commitment_id " +
+		"where c.status in (?)" + connectionModeFilter(connectionMode) + " " +
+		"group by c.system_content_id, p.padded_piece_size) t"
+}
+
+// inProgressFrom selects the content logs whose status is in neither of the two lists bound to its placeholders
+func inProgressFrom(connectionMode string) string {
+	return "(select * from content_logs c where c.status not in (?) and c.status not in (?)" + connectionModeFilter(connectionMode) + ") t"
+}
+
+func connectionModeFilter(connectionMode string) string {
+	if connectionMode == "" {
+		return ""
+	}
+	return " and c.connection_mode = '" + connectionMode + "'"
+}
+
+// TimeSeriesMetricByName returns the registered metric called name, or nil
+func TimeSeriesMetricByName(name string) *TimeSeriesMetric {
+	for _, metric := range TimeSeriesMetrics {
+		if metric.Name == name {
+			return metric
+		}
+	}
+	return nil
+}
+
+// TimeSeriesQuery selects the buckets of a time series, From is inclusive and To exclusive
+type TimeSeriesQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// ParseTimeSeriesQuery parses the range, see ParseTimeRange, and the interval, which defaults to day.
+// When to is not set it is the end of the current minute rather than now, so the default range can be cached.
+// error - ErrBadParams, bad range, unknown interval or more than MaxTimeSeriesPoints buckets
+func ParseTimeSeriesQuery(from, to, interval string) (*TimeSeriesQuery, error) {
+	query := &TimeSeriesQuery{Interval: "day"}
+
+	if to == "" {
+		to = time.Now().UTC().Truncate(time.Minute).Add(time.Minute).Format(time.RFC3339)
+	}
+
+	var err error
+	if query.From, query.To, err = ParseTimeRange(from, to); err != nil {
+		return nil, err
+	}
+
+	if interval != "" {
+		if _, ok := timeSeriesIntervalLength[interval]; !ok {
+			return nil, fmt.Errorf("%w: interval must be one of %s", ErrBadParams, strings.Join(TimeSeriesIntervals, ", "))
+		}
+		query.Interval = interval
+	}
+
+	if query.To.Sub(query.From)/timeSeriesIntervalLength[query.Interval] >= MaxTimeSeriesPoints {
+		return nil, fmt.Errorf("%w: more than %d %s buckets between from and to, use a larger interval", ErrBadParams, MaxTimeSeriesPoints, query.Interval)
+	}
+
+	return query, nil
+}
+
+// cacheKey a series is cached per metric, interval and range
+func (q *TimeSeriesQuery) cacheKey(prefix string) string {
+	return prefix + ":" + q.Interval + ":" + q.From.Format(time.RFC3339Nano) + ":" + q.To.Format(time.RFC3339Nano)
+}
+
+// ParseTimeRange parses from and to, RFC3339 times or YYYY-MM-DD dates. To defaults to now and from to 30 days before to.
+// error - ErrBadParams, unparsable time or from not before to
+func ParseTimeRange(from, to string) (fromTime, toTime time.Time, err error) {
+	toTime = time.Now().UTC()
+	if to != "" {
+		if toTime, err = parseTimeSeriesTime("to", to); err != nil {
+			return fromTime, toTime, err
+		}
+	}
+
+	fromTime = toTime.Add(-defaultTimeSeriesSpan)
+	if from != "" {
+		if fromTime, err = parseTimeSeriesTime("from", from); err != nil {
+			return fromTime, toTime, err
+		}
+	}
+
+	if !fromTime.Before(toTime) {
+		return fromTime, toTime, fmt.Errorf("%w: from must be before to", ErrBadParams)
+	}
+	return fromTime, toTime, nil
+}
+
+func parseTimeSeriesTime(param, value string) (time.Time, error) {
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s expects an RFC3339 time or a YYYY-MM-DD date, got %q", ErrBadParams, param, value)
+}
+
+// TimeSeries is a metric bucketed over time, buckets without data have a 0 value
+type TimeSeries struct {
+	Metric   string             `json:"metric"`
+	Interval string             `json:"interval"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Points   []*TimeSeriesPoint `json:"points"`
+
+	// ComputedAt time the series was read from the log tables, it is older than now when served from the cache
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// TimeSeriesPoint is the value of a bucket, Bucket is its start in UTC
+type TimeSeriesPoint struct {
+	Bucket time.Time `gorm:"column:bucket" json:"bucket"`
+	Value  int64     `gorm:"column:value" json:"value"`
+}
+
+// GetTimeSeries buckets the metric called name over the range of query, every bucket from the one holding From to the
+// one holding the last instant before To is returned
+// error - ErrBadParams for an unknown metric, db error
+func GetTimeSeries(name string, query *TimeSeriesQuery) (*TimeSeries, error) {
+	metric := TimeSeriesMetricByName(name)
+	if metric == nil {
+		names := make([]string, 0, len(TimeSeriesMetrics))
+		for _, m := range TimeSeriesMetrics {
+			names = append(names, m.Name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w: unknown metric %s, known metrics are %s", ErrBadParams, name, strings.Join(names, ", "))
+	}
+
+	// the series is read from the log tables rather than a view, it expires with the Cacher ttl
+	var series TimeSeries
+	err := cacheFetch(query.cacheKey("timeSeries:"+metric.Name), &series, func() (interface{}, error) {
+		series := &TimeSeries{Metric: metric.Name, Interval: query.Interval, From: query.From, To: query.To,
+			Points: []*TimeSeriesPoint{}, ComputedAt: time.Now().UTC()}
+		last := query.To.Add(-time.Microsecond)
+
+		args := []interface{}{query.Interval, query.From, query.Interval, last, query.Interval, query.Interval}
+		args = append(args, metric.Args...)
+		args = append(args, query.From, query.To)
+
+		// buckets are truncated in UTC so they do not depend on the session time zone
+		err := DB.Raw(`select b.bucket, coalesce(m.value, 0)::int8 as value
+			from generate_series(date_trunc(?, ?::timestamptz at time zone 'UTC'), date_trunc(?, ?::timestamptz at time zone 'UTC'), ('1 ' || ?)::interval) as b(bucket)
+			left join (
+				select date_trunc(?, t.created_at at time zone 'UTC') as bucket, `+metric.Value+` as value
+				from `+metric.From+`
+				where t.created_at >= ? and t.created_at < ?
+				group by 1
+			) m on m.bucket = b.bucket
+			order by b.bucket`, args...).
+			Scan(&series.Points).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, point := range series.Points {
+			point.Bucket = time.Date(point.Bucket.Year(), point.Bucket.Month(), point.Bucket.Day(),
+				point.Bucket.Hour(), point.Bucket.Minute(), point.Bucket.Second(), 0, time.UTC)
+		}
+		return series, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return dealsAttempatedInRange, nil
+	return &series, nil
+}
+
+// GetDealsAttemptedInRange counts the content log entries created from from, inclusive, to to, exclusive
+// error - db error
+func GetDealsAttemptedInRange(from, to time.Time) (int64, error) {
+	var dealsAttempted int64
+	row := DB.Raw("select count(*) from content_logs where created_at >= ? and created_at < ?", from, to).Row()
+	if err := row.Scan(&dealsAttempted); err != nil {
+		return 0, err
+	}
+	return dealsAttempted, nil
 }
@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ViewSQLProvider returns the create sql of a materialized view
+type ViewSQLProvider interface {
+	ViewSQL(name string) (string, error)
+}
+
+// ViewSQL is the provider used by MaterializedView.CreateSQL, main sets it to the sql embedded in the binary.
+// It defaults to reading sql/views from the working directory.
+var ViewSQL ViewSQLProvider = NewViewSQLProvider(os.DirFS("sql/views"), "")
+
+// viewSQLFiles reads <name>.sql from files, unless overrideDir holds a file of the same name
+type viewSQLFiles struct {
+	files       fs.FS
+	overrideDir string
+}
+
+// NewViewSQLProvider returns a provider reading <name>.sql from files. When overrideDir is set, a <name>.sql file
+// there takes precedence, it is read on every call so view sql can be patched without a rebuild or restart.
+func NewViewSQLProvider(files fs.FS, overrideDir string) ViewSQLProvider {
+	return &viewSQLFiles{files: files, overrideDir: overrideDir}
+}
+
+func (p *viewSQLFiles) ViewSQL(name string) (string, error) {
+	file := name + ".sql"
+
+	if p.overrideDir != "" {
+		b, err := os.ReadFile(filepath.Join(p.overrideDir, file))
+		if err == nil {
+			return string(b), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("unable to read override sql of view %s: %w", name, err)
+		}
+	}
+
+	b, err := fs.ReadFile(p.files, file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read create sql of view %s: %w", name, err)
+	}
+	return string(b), nil
+}
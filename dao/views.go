@@ -0,0 +1,185 @@
+package dao
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// ViewGroupGlobalStats the single row view behind the global totals
+	ViewGroupGlobalStats = "global_stats"
+
+	// ViewGroupAllTableViews copies of the log tables
+	ViewGroupAllTableViews = "all_table_views"
+
+	// ViewGroupDashboard top sp and delta node rankings
+	ViewGroupDashboard = "dashboard"
+
+	// ViewGroupOnboarded onboarded deals by sp, node and api key
+	ViewGroupOnboarded = "onboarded"
+)
+
+// MaterializedView is a materialized view managed by the service
+type MaterializedView struct {
+	// Name of the view in the database, its create sql is <Name>.sql of the ViewSQL provider
+	Name string `json:"name"`
+
+	// Group the view is refreshed with
+	Group string `json:"group"`
+
+	// DependsOn views that must be created and refreshed before this one
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Concurrently the view has a unique index and can be refreshed without locking out readers
+	Concurrently bool `json:"concurrently"`
+}
+
+// Views is the registry of materialized views, in creation order
+var Views = []*MaterializedView{
+	{Name: "mv_content_logs_tbl", Group: ViewGroupAllTableViews, Concurrently: true},
+	{Name: "mv_content_deal_logs_tbl", Group: ViewGroupAllTableViews, Concurrently: true},
+	{Name: "mv_content_deal_proposal_logs_tbl", Group: ViewGroupAllTableViews, Concurrently: true},
+	{Name: "mv_content_miner_logs_tbl", Group: ViewGroupAllTableViews, Concurrently: true},
+
+	{Name: "mv_top_sp_miners", Group: ViewGroupDashboard, Concurrently: true},
+	{Name: "mv_top_delta_nodes", Group: ViewGroupDashboard, Concurrently: true},
+
+	{Name: "mv_onboarded_deals_by_sp_uuid_key", Group: ViewGroupOnboarded},
+
+	{Name: "mv_global_totals", Group: ViewGroupGlobalStats, Concurrently: true},
+}
+
+// CreateSQL returns the sql creating the view
+func (v *MaterializedView) CreateSQL() (string, error) {
+	return ViewSQL.ViewSQL(v.Name)
+}
+
+// RefreshSQL is the statement refreshing the view
+func (v *MaterializedView) RefreshSQL() string {
+	if v.Concurrently {
+		return "REFRESH MATERIALIZED VIEW CONCURRENTLY " + v.Name
+	}
+	return "REFRESH MATERIALIZED VIEW " + v.Name
+}
+
+// ViewByName returns the registered view called name, or nil
+func ViewByName(name string) *MaterializedView {
+	for _, v := range Views {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// ViewGroups returns the names of the refresh groups, sorted
+func ViewGroups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, v := range Views {
+		if !seen[v.Group] {
+			seen[v.Group] = true
+			groups = append(groups, v.Group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// ViewsInGroup returns the views of group, each one after the views it depends on
+func ViewsInGroup(group string) []*MaterializedView {
+	var views []*MaterializedView
+	for _, v := range orderedViews() {
+		if v.Group == group {
+			views = append(views, v)
+		}
+	}
+	return views
+}
+
+// ViewsFor resolves a refresh target, either a group name or the name of a single view.
+// error - ErrUnknownView when name is neither
+func ViewsFor(name string) ([]*MaterializedView, error) {
+	if views := ViewsInGroup(name); len(views) > 0 {
+		return views, nil
+	}
+	if v := ViewByName(name); v != nil {
+		return []*MaterializedView{v}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownView, name)
+}
+
+// orderedViews returns the registry sorted so that every view comes after its dependencies,
+// views without dependencies between them keep their registry order
+func orderedViews() []*MaterializedView {
+	ordered := make([]*MaterializedView, 0, len(Views))
+	visited := make(map[string]bool, len(Views))
+
+	var visit func(v *MaterializedView)
+	visit = func(v *MaterializedView) {
+		if visited[v.Name] {
+			return
+		}
+		visited[v.Name] = true
+		for _, dep := range v.DependsOn {
+			if d := ViewByName(dep); d != nil {
+				visit(d)
+			}
+		}
+		ordered = append(ordered, v)
+	}
+
+	for _, v := range Views {
+		visit(v)
+	}
+	return ordered
+}
+
+// ValidateViews checks that view names are unique, every view has create sql and dependencies are registered and acyclic
+func ValidateViews() error {
+	seen := make(map[string]bool, len(Views))
+	for _, v := range Views {
+		if seen[v.Name] {
+			return fmt.Errorf("view %s registered twice", v.Name)
+		}
+		seen[v.Name] = true
+
+		if _, err := v.CreateSQL(); err != nil {
+			return err
+		}
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(Views))
+	var visit func(v *MaterializedView) error
+	visit = func(v *MaterializedView) error {
+		switch state[v.Name] {
+		case visiting:
+			return fmt.Errorf("view %s has a dependency cycle", v.Name)
+		case done:
+			return nil
+		}
+		state[v.Name] = visiting
+		for _, dep := range v.DependsOn {
+			d := ViewByName(dep)
+			if d == nil {
+				return fmt.Errorf("view %s depends on unknown view %s", v.Name, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[v.Name] = done
+		return nil
+	}
+
+	for _, v := range Views {
+		if err := visit(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
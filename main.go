@@ -2,22 +2,21 @@ package main
 
 import (
 	"context"
+	"embed"
 	"fmt"
 	"github.com/application-research/delta-metrics-rest/api"
 	"github.com/application-research/delta-metrics-rest/dao"
-	"github.com/application-research/delta-metrics-rest/model"
 	"github.com/droundy/goopt"
 	"github.com/gin-gonic/gin"
-	"github.com/go-co-op/gocron"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mssql"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
-	explru "github.com/paskal/golang-lru/simplelru"
 	"github.com/spf13/viper"
 	"github.com/swaggo/files"       // swagger embed files
 	"github.com/swaggo/gin-swagger" // gin-swagger middleware
+	"io/fs"
 	"log"
 	"os"
 	"os/signal"
@@ -25,9 +24,29 @@ import (
 	"time"
 )
 
+// sqlFiles view definitions built into the binary, so the server does not depend on its working directory
+//
+//go:embed sql/views/*.sql
+var sqlFiles embed.FS
+
+// CacheSize default byte budget of the memory cache, CACHE_MAX_BYTES overrides it
 const CacheSize = 1024 * 1024 * 1024 // 1GB
+
+// CacheDuration default ttl of cached stats, CACHE_DURATION overrides it
 const CacheDuration = time.Hour * 4
-const CachePurgeEveryDuration = time.Hour * 4
+
+// CacheStaleWindow default time a stale stat is served while it is recomputed, CACHE_STALE_WINDOW overrides it
+const CacheStaleWindow = time.Minute * 15
+
+// CacheSyncInterval default time between reads of the view refreshes of other instances by the memory cache,
+// CACHE_SYNC_INTERVAL overrides it
+const CacheSyncInterval = time.Second * 30
+
+// NodeStaleAfter default time without logs after which a delta node is reported stale, NODE_STALE_AFTER overrides it
+const NodeStaleAfter = time.Hour
+
+// RedisTimeout default dial and command timeout of the redis cache, REDIS_TIMEOUT overrides it
+const RedisTimeout = time.Second * 2
 
 var (
 	// BuildDate date string of when build was performed filled in by -X compile flag
@@ -59,8 +78,10 @@ func GinServer() (err error) {
 	router := gin.Default()
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 
-	api.ConfigGinRouter(router)
-	router.Run(":8080")
+	if err = api.ConfigGinRouter(router); err != nil {
+		log.Fatalf("Error configuring routes, the error is '%v'", err)
+	}
+	err = router.Run(":8080")
 	if err != nil {
 		log.Fatalf("Error starting server, the error is '%v'", err)
 	}
@@ -143,27 +164,77 @@ func main() {
 
 	dao.DB = db
 
-	db.AutoMigrate(
-		&model.ContentDealLogs{},
-		&model.ContentDealProposalLogs{},
-		&model.ContentDealProposalParametersLogs{},
-		&model.ContentLogs{},
-		&model.ContentMinerLogs{},
-		&model.ContentWalletLogs{},
-		&model.DeltaNodeGeoLocations{},
-		&model.DeltaStartupLogs{},
-		&model.InstanceMetaLogs{},
-		&model.LogEvents{},
-		&model.PieceCommitmentLogs{},
-		&model.WalletLogs{},
-	)
+	viewSQLFiles, err := fs.Sub(sqlFiles, "sql/views")
+	if err != nil {
+		log.Fatalf("Got error when reading embedded view sql, the error is '%v'", err)
+	}
+	dao.ViewSQL = dao.NewViewSQLProvider(viewSQLFiles, viper.GetString("VIEW_SQL_OVERRIDE_DIR"))
+
+	if err = dao.ValidateViews(); err != nil {
+		log.Fatalf("Error in the view registry, the error is '%v'", err)
+	}
+
+	if len(goopt.Args) > 0 && goopt.Args[0] == "migrate" {
+		os.Exit(RunMigrate(goopt.Args[1:]))
+	}
+
+	if viper.GetString("MIGRATE_ON_START") != "false" {
+		applied, err := dao.MigrateUp()
+		if err != nil {
+			log.Fatalf("Got error when migrating database, the error is '%v'", err)
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied migration %d %s\n", migration.Version, migration.Name)
+		}
+	}
+
+	if err = dao.RecoverRefreshJobs(); err != nil {
+		log.Printf("Got error when recovering view refresh jobs, the error is '%v'", err)
+	}
+
+	if err = api.ConfigureAuth(); err != nil {
+		log.Fatalf("Error while reading auth config, the error is '%v'", err)
+	}
+
+	if err = api.ConfigureHTTPCache(); err != nil {
+		log.Fatalf("Error while reading http cache config, the error is '%v'", err)
+	}
+
+	if err = api.ConfigureSensitiveColumns(); err != nil {
+		log.Fatalf("Error while reading sensitive column config, the error is '%v'", err)
+	}
 
 	dao.Logger = func(ctx context.Context, sql string) {
 		fmt.Printf("SQL: %s\n", sql)
 	}
 
 	// cache
-	dao.Cacher = explru.NewExpirableLRU(CacheSize, nil, CacheDuration, CachePurgeEveryDuration)
+	if dao.Cacher, err = NewCache(); err != nil {
+		log.Fatalf("Error while reading cache config, the error is '%v'", err)
+	}
+	dao.CachePrewarm = viper.GetBool("CACHE_PREWARM")
+	if backend := viper.GetString("CACHE_BACKEND"); backend == "" || backend == "memory" {
+		interval := CacheSyncInterval
+		if viper.IsSet("CACHE_SYNC_INTERVAL") {
+			d, err := time.ParseDuration(viper.GetString("CACHE_SYNC_INTERVAL"))
+			if err != nil || d < 0 {
+				log.Fatalf("Invalid CACHE_SYNC_INTERVAL %q, expected a duration such as 30s", viper.GetString("CACHE_SYNC_INTERVAL"))
+			}
+			interval = d
+		}
+		if interval > 0 {
+			dao.WatchRefreshes(interval)
+		}
+	}
+
+	dao.NodeStaleAfter = NodeStaleAfter
+	if viper.IsSet("NODE_STALE_AFTER") {
+		d, err := time.ParseDuration(viper.GetString("NODE_STALE_AFTER"))
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid NODE_STALE_AFTER %q, expected a duration such as 1h", viper.GetString("NODE_STALE_AFTER"))
+		}
+		dao.NodeStaleAfter = d
+	}
 
 	// Initialize Refresh Views
 	RefreshDBViews()
@@ -172,43 +243,71 @@ func main() {
 	LoopForever()
 }
 
+// RefreshDBViews schedules the refresh of every view group
 func RefreshDBViews() {
+	if err := api.StartRefreshScheduler(); err != nil {
+		log.Fatalf("Error scheduling view refreshes, the error is '%v'", err)
+	}
+}
 
-	s := gocron.NewScheduler(time.UTC)
-
-	// Every starts the job immediately and then runs at the
-	// specified interval
-	jobRefreshGlobalStats, err := s.Every(4).Hours().Do(func() {
-		fmt.Println("Refresh Stats Views")
-		refreshViews, err := os.ReadFile("sql/views/refresh_mv_stats.sql")
-		refreshViewsStr := string(refreshViews)
+// NewCache builds the stats cache selected by CACHE_BACKEND, memory (the default) or redis
+func NewCache() (dao.Cache, error) {
+	cacheDuration := CacheDuration
+	if viper.IsSet("CACHE_DURATION") {
+		d, err := time.ParseDuration(viper.GetString("CACHE_DURATION"))
 		if err != nil {
-			log.Printf("Got error when reading refresh_mv_stats.sql, the error is '%v'", err)
+			return nil, fmt.Errorf("invalid CACHE_DURATION: %w", err)
 		}
-		if err := dao.DB.Exec(refreshViewsStr); err != nil {
-			log.Println(err)
+		cacheDuration = d
+	}
+
+	dao.CacheDuration = cacheDuration
+
+	dao.CacheStaleWindow = CacheStaleWindow
+	if viper.IsSet("CACHE_STALE_WINDOW") {
+		d, err := time.ParseDuration(viper.GetString("CACHE_STALE_WINDOW"))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid CACHE_STALE_WINDOW %q", viper.GetString("CACHE_STALE_WINDOW"))
 		}
-	})
+		dao.CacheStaleWindow = d
+	}
+
+	ttls, err := dao.ParseCacheTTLs(viper.GetString("CACHE_TTLS"))
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
+	dao.CacheTTLs = ttls
 
-	jobRefreshAllTables, err := s.Every(4).Hours().Do(func() {
-		fmt.Println("Refresh All Table Views")
-		refreshViews, err := os.ReadFile("sql/views/refresh_all_tables.sql")
-		refreshViewsStr := string(refreshViews)
-		if err != nil {
-			log.Println(err)
+	switch backend := viper.GetString("CACHE_BACKEND"); backend {
+	case "", "memory":
+		maxBytes := int64(CacheSize)
+		if viper.IsSet("CACHE_MAX_BYTES") {
+			maxBytes = viper.GetInt64("CACHE_MAX_BYTES")
 		}
-		if err := dao.DB.Exec(refreshViewsStr); err != nil {
-			log.Println(err)
+		return dao.NewMemoryCache(maxBytes, cacheDuration), nil
+
+	case "redis":
+		addr := viper.GetString("REDIS_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("cache backend redis requires REDIS_ADDR")
 		}
-	})
 
-	fmt.Println(jobRefreshGlobalStats)
-	fmt.Println(jobRefreshAllTables)
+		timeout := RedisTimeout
+		if viper.IsSet("REDIS_TIMEOUT") {
+			if timeout, err = time.ParseDuration(viper.GetString("REDIS_TIMEOUT")); err != nil {
+				return nil, fmt.Errorf("invalid REDIS_TIMEOUT: %w", err)
+			}
+		}
 
-	s.StartAsync()
+		prefix := "dmr:"
+		if viper.IsSet("CACHE_KEY_PREFIX") {
+			prefix = viper.GetString("CACHE_KEY_PREFIX")
+		}
+		return dao.NewRedisCache(addr, viper.GetString("REDIS_PASSWORD"), viper.GetInt("REDIS_DB"), prefix, cacheDuration, timeout), nil
+
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q, expected memory or redis", backend)
+	}
 }
 
 // LoopForever on signal processing
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/application-research/delta-metrics-rest/dao"
+)
+
+// RunMigrate runs the migrate subcommand, up applies pending migrations and recreates views changed on disk,
+// down reverts the latest migration and status lists migrations and view drift. Returns the process exit code.
+func RunMigrate(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("usage: delta-metrics-rest migrate up|down|status")
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := dao.MigrateUp()
+		if err != nil {
+			fmt.Printf("migrate up failed: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		for _, migration := range applied {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		printMigrationStatus()
+		return 0
+
+	case "down":
+		reverted, err := dao.MigrateDown()
+		if err != nil {
+			fmt.Printf("migrate down failed: %v\n", err)
+			return 1
+		}
+		if reverted == nil {
+			fmt.Println("no applied migrations")
+		} else {
+			fmt.Printf("reverted %d %s\n", reverted.Version, reverted.Name)
+		}
+		return 0
+
+	case "status":
+		return printMigrationStatus()
+
+	default:
+		fmt.Printf("unknown migrate command %q, expected up, down or status\n", args[0])
+		return 2
+	}
+}
+
+// printMigrationStatus prints the migrations and views, it returns 1 when there are pending migrations or drifted views
+func printMigrationStatus() int {
+	status, err := dao.GetMigrationStatus()
+	if err != nil {
+		fmt.Printf("migrate status failed: %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, migration := range status.Applied {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, migration.AppliedAt.Format("2006-01-02 15:04:05"))
+	}
+	for _, migration := range status.Pending {
+		fmt.Fprintf(w, "%d\t%s\tpending\n", migration.Version, migration.Name)
+	}
+	fmt.Fprintln(w)
+
+	drifted := 0
+	fmt.Fprintln(w, "VIEW\tSTATE")
+	for _, view := range status.Views {
+		if view.State != dao.ViewStateOK {
+			drifted++
+		}
+		fmt.Fprintf(w, "%s\t%s\n", view.Name, view.State)
+	}
+	w.Flush()
+
+	if len(status.Pending) > 0 || drifted > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,9 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Action CRUD actions
 type Action int32
@@ -25,6 +28,9 @@ var (
 	FetchDDL = Action(5)
 
 	tables map[string]*TableInfo
+
+	// DefaultSensitiveColumns columns that are always treated as sensitive, see MarkSensitiveColumns
+	DefaultSensitiveColumns = []string{"wallet_logs.private_key", "*.requesting_api_key"}
 )
 
 func init() {
@@ -42,6 +48,10 @@ func init() {
 	tables["log_events"] = log_eventsTableInfo
 	tables["piece_commitment_logs"] = piece_commitment_logsTableInfo
 	tables["wallet_logs"] = wallet_logsTableInfo
+
+	if err := MarkSensitiveColumns(DefaultSensitiveColumns); err != nil {
+		panic(err)
+	}
 }
 
 // String describe the action
@@ -100,6 +110,7 @@ type ColumnInfo struct {
 	ColumnType         string `json:"columnType"`
 	ColumnLength       int64  `json:"columnLength"`
 	DefaultValue       string `json:"defaultValue"`
+	Sensitive          bool   `json:"sensitive"`
 }
 
 // GetTableInfo retrieve TableInfo for a table
@@ -107,3 +118,39 @@ func GetTableInfo(name string) (*TableInfo, bool) {
 	val, ok := tables[name]
 	return val, ok
 }
+
+// MarkSensitiveColumns flags columns as sensitive, each spec is "table.column" or "*.column" for every table with that column.
+// Sensitive column values are redacted from api responses unless the caller is an admin.
+func MarkSensitiveColumns(specs []string) error {
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		parts := strings.Split(spec, ".")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid sensitive column %q, expected table.column", spec)
+		}
+
+		found := false
+		for name, table := range tables {
+			if parts[0] != "*" && parts[0] != name {
+				continue
+			}
+
+			for _, column := range table.Columns {
+				if column.Name == parts[1] {
+					column.Sensitive = true
+					found = true
+				}
+			}
+		}
+
+		if !found && parts[0] != "*" {
+			return fmt.Errorf("unknown sensitive column %q", spec)
+		}
+	}
+
+	return nil
+}
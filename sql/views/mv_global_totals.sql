@@ -0,0 +1,79 @@
+DROP MATERIALIZED VIEW IF EXISTS mv_global_totals;
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_global_totals
+AS
+with deals as (
+    select
+        count(*) as attempted,
+        count(*) filter (where c.created_at > now() - interval '24 hours') as attempted_past_24h,
+        count(*) filter (where c.connection_mode = 'e2e') as e2e_attempted,
+        count(*) filter (where c.connection_mode = 'import') as import_attempted,
+        count(*) filter (where c.status in ('deal-proposal-sent','transfer-started','transfer-finished')) as succeeded,
+        count(*) filter (where c.status in ('deal-proposal-sent','transfer-started','transfer-finished') and c.created_at > now() - interval '24 hours') as succeeded_past_24h,
+        count(*) filter (where c.connection_mode = 'e2e' and c.status in ('transfer-started','transfer-finished')) as e2e_succeeded,
+        count(*) filter (where c.connection_mode = 'import' and c.status = 'deal-proposal-sent') as import_succeeded,
+        count(*) filter (where c.status not in ('transfer-failed','deal-proposal-failed','piece-computing-failed','failed-to-process','deal-proposal-sent','transfer-started','transfer-finished') and c.created_at > now() - interval '24 hours') as in_progress_24h,
+        count(*) filter (where c.connection_mode = 'e2e' and c.status not in ('transfer-failed','deal-proposal-failed','piece-computing-failed','failed-to-process','deal-proposal-sent','transfer-started','transfer-finished') and c.created_at > now() - interval '24 hours') as e2e_in_progress_24h,
+        count(*) filter (where c.connection_mode = 'import' and c.status not in ('transfer-failed','deal-proposal-failed','piece-computing-failed','failed-to-process','deal-proposal-sent','transfer-started','transfer-finished') and c.created_at > now() - interval '24 hours') as import_in_progress_24h
+    from content_logs c
+),
+-- a content counts its size once however many log rows it has
+content_sizes as (
+    select
+        c.size,
+        bool_or(c.created_at > now() - interval '24 hours') as past_24h,
+        bool_or(c.connection_mode = 'e2e') as is_e2e,
+        bool_or(c.connection_mode = 'import') as is_import
+    from content_logs c
+    group by c.size, c.system_content_id
+),
+deal_sizes as (
+    select
+        p.padded_piece_size as size,
+        bool_or(c.created_at > now() - interval '24 hours') as past_24h,
+        bool_or(c.connection_mode = 'e2e' and c.status in ('transfer-started','transfer-finished')) as is_e2e,
+        bool_or(c.connection_mode = 'import' and c.status = 'deal-proposal-sent') as is_import
+    from content_logs c
+    join piece_commitment_logs p on c.piece_commitment_id = p.system_content_piece_commitment_id
+    where c.status in ('deal-proposal-sent','transfer-started','transfer-finished')
+    group by c.system_content_id, p.padded_piece_size
+),
+pieces as (
+    select
+        p.size,
+        count(p.piece) as attempted,
+        count(p.piece) filter (where p.status = 'committed') as committed,
+        bool_or(p.status = 'committed') as any_committed
+    from piece_commitment_logs p
+    where p.piece is not null
+    group by p.size, p.piece
+)
+select
+    1 as id,
+    now() as computed_at,
+    d.attempted as total_deals_attempted,
+    d.attempted_past_24h as total_deals_attempted_past_24h,
+    (select coalesce(sum(size), 0)::int8 from content_sizes) as total_deals_attempted_size,
+    (select coalesce(sum(size) filter (where past_24h), 0)::int8 from content_sizes) as total_deals_attempted_size_past_24h,
+    d.e2e_attempted as total_e2e_deals_attempted,
+    (select coalesce(sum(size) filter (where is_e2e), 0)::int8 from content_sizes) as total_e2e_deals_attempted_size,
+    d.import_attempted as total_import_deals_attempted,
+    (select coalesce(sum(size) filter (where is_import), 0)::int8 from content_sizes) as total_import_deals_attempted_size,
+    d.succeeded as total_deals_succeeded,
+    d.succeeded_past_24h as total_deals_succeeded_past_24h,
+    (select coalesce(sum(size), 0)::int8 from deal_sizes) as total_deals_succeeded_size,
+    (select coalesce(sum(size) filter (where past_24h), 0)::int8 from deal_sizes) as total_deals_succeeded_size_past_24h,
+    d.e2e_succeeded as total_e2e_succeeded,
+    (select coalesce(sum(size) filter (where is_e2e), 0)::int8 from deal_sizes) as total_e2e_succeeded_size,
+    d.import_succeeded as total_import_succeeded,
+    (select coalesce(sum(size) filter (where is_import), 0)::int8 from deal_sizes) as total_import_succeeded_size,
+    (select coalesce(sum(committed), 0)::int8 from pieces) as total_piece_commitments_compute_succeeded,
+    (select coalesce(sum(size) filter (where any_committed), 0)::int8 from pieces) as total_piece_commitments_compute_succeeded_size,
+    (select coalesce(sum(attempted), 0)::int8 from pieces) as total_piece_commitments_compute_attempted,
+    (select coalesce(sum(size), 0)::int8 from pieces) as total_piece_commitments_compute_attempted_size,
+    (select count(distinct miner) from content_miner_logs) as total_number_of_sps_worked_with,
+    (select count(distinct delta_node_uuid) from delta_startup_logs) as total_number_of_unique_delta_nodes,
+    d.in_progress_24h as total_in_progress_deals_24h,
+    d.e2e_in_progress_24h as total_in_progress_e2e_deals_24h,
+    d.import_in_progress_24h as total_in_progress_import_deals_24h
+from deals d;
+CREATE UNIQUE INDEX ON mv_global_totals(id);
@@ -8,3 +8,6 @@ where c.piece_commitment_id = p.system_content_piece_commitment_id
   and c.system_content_id = cd.content
   and c.status in ('deal-proposal-sent','transfer-started','transfer-finished')
 group by system_content_id, p.padded_piece_size,c.delta_node_uuid,c.requesting_api_key,cd.miner,cd.deal_uuid, cd.deal_id;
+CREATE INDEX ON mv_onboarded_deals_by_sp_uuid_key(miner);
+CREATE INDEX ON mv_onboarded_deals_by_sp_uuid_key(delta_node_uuid);
+CREATE INDEX ON mv_onboarded_deals_by_sp_uuid_key(requesting_api_key);
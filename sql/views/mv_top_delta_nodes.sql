@@ -0,0 +1,40 @@
+DROP MATERIALIZED VIEW IF EXISTS mv_top_delta_nodes;
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_top_delta_nodes AS
+with windows (time_window, since) as (
+    values ('24h', now() - interval '24 hours'),
+           ('7d', now() - interval '7 days'),
+           ('30d', now() - interval '30 days'),
+           ('all', '-infinity'::timestamptz)
+),
+-- one row per content handled by the node
+node_deals as (
+    select
+        cl.delta_node_uuid,
+        min(cl.created_at) as created_at,
+        max(cl.size) as size,
+        bool_or(cl.status in ('transfer-started','transfer-finished','deal-proposal-sent')) as succeeded
+    from content_logs cl
+    where cl.delta_node_uuid <> ''
+    group by cl.delta_node_uuid, cl.system_content_id
+),
+latest_meta as (
+    select distinct on (iml.delta_node_uuid) iml.delta_node_uuid, iml.os_details, iml.public_ip
+    from instance_meta_logs iml
+    order by iml.delta_node_uuid, iml.created_at desc
+)
+select
+    w.time_window,
+    d.delta_node_uuid,
+    m.os_details,
+    m.public_ip,
+    count(*) as deals_attempted,
+    count(*) filter (where d.succeeded) as deals_succeeded,
+    coalesce(sum(d.size) filter (where d.succeeded), 0)::int8 as bytes_onboarded,
+    round(count(*) filter (where d.succeeded)::numeric / count(*), 4)::float8 as success_rate,
+    min(d.created_at) as first_seen,
+    max(d.created_at) as last_seen
+from windows w
+join node_deals d on d.created_at >= w.since
+left join latest_meta m on m.delta_node_uuid = d.delta_node_uuid
+group by w.time_window, d.delta_node_uuid, m.os_details, m.public_ip;
+CREATE UNIQUE INDEX ON mv_top_delta_nodes(time_window, delta_node_uuid);
@@ -0,0 +1,33 @@
+DROP MATERIALIZED VIEW IF EXISTS mv_top_sp_miners;
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_top_sp_miners AS
+with windows (time_window, since) as (
+    values ('24h', now() - interval '24 hours'),
+           ('7d', now() - interval '7 days'),
+           ('30d', now() - interval '30 days'),
+           ('all', '-infinity'::timestamptz)
+),
+-- one row per content a delta node proposed to the miner
+sp_deals as (
+    select
+        cml.miner,
+        min(cml.created_at) as created_at,
+        max(cl.size) as size,
+        bool_or(cl.status in ('transfer-started','transfer-finished','deal-proposal-sent')) as succeeded
+    from content_miner_logs cml
+    join content_logs cl on cl.system_content_id = cml.content and cl.delta_node_uuid = cml.delta_node_uuid
+    where cml.miner <> ''
+    group by cml.miner, cml.content, cml.delta_node_uuid
+)
+select
+    w.time_window,
+    d.miner,
+    count(*) as deals_attempted,
+    count(*) filter (where d.succeeded) as deals_succeeded,
+    coalesce(sum(d.size) filter (where d.succeeded), 0)::int8 as bytes_onboarded,
+    round(count(*) filter (where d.succeeded)::numeric / count(*), 4)::float8 as success_rate,
+    min(d.created_at) as first_seen,
+    max(d.created_at) as last_seen
+from windows w
+join sp_deals d on d.created_at >= w.since
+group by w.time_window, d.miner;
+CREATE UNIQUE INDEX ON mv_top_sp_miners(time_window, miner);
@@ -1,4 +0,0 @@
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_content_logs_tbl;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_content_deal_logs_tbl;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_content_deal_proposal_logs_tbl;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_content_miner_logs_tbl;
\ No newline at end of file
@@ -1,2 +0,0 @@
-REFRESH MATERIALIZED VIEW mv_top_sp_miners;
-REFRESH MATERIALIZED VIEW mv_top_delta_nodes;
\ No newline at end of file
@@ -1 +0,0 @@
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_onboarded_deals_by_sp_uuid_key;
\ No newline at end of file
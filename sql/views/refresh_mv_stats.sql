@@ -1,28 +0,0 @@
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_attempted;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_attempted_past_24h;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_attempted_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_attempted_size_past_24h;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_e2e_deals_attempted;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_e2e_deals_attempted_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_import_deals_attempted;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_import_deals_attempted_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_succeeded;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_succeeded_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_succeeded_past_24h;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_deals_succeeded_size_past_24h;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_e2e_deals_succeeded;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_e2e_deals_succeeded_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_import_deals_succeeded;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_import_deals_succeeded_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_commp_compute_succeeded;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_commp_compute_succeeded_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_commp_compute_attempted;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_commp_compute_attempted_size;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_number_of_sps_work_with;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_number_of_unique_delta_nodes;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_total_in_progress_deals_24;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_total_in_progress_e2e_deals_24;
-REFRESH MATERIALIZED VIEW CONCURRENTLY mv_total_in_progress_import_deals_24;
-
-
-